@@ -0,0 +1,397 @@
+package chkjson
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"unicode/utf8"
+	"unsafe"
+)
+
+// AppendCanonical appends the canonical form of src to dst as defined by RFC
+// 8785, the JSON Canonicalization Scheme (JCS), returning the updated dst and
+// whether src was valid and could be canonicalized. If it could not, this
+// returns nil.
+//
+// Canonical JSON has no whitespace, has object members sorted by the UTF-16
+// code units of their keys, serializes numbers as ECMAScript does, and escapes
+// strings minimally.
+//
+// JCS requires I-JSON input: strings must be valid UTF-8 and must not contain
+// escaped unpaired surrogate halves, object keys must be unique once
+// unescaped, and numbers must fit in a float64. Input breaking any of these
+// rules is rejected.
+//
+// Canonicalizing can grow numbers, meaning dst must not share memory with src.
+func AppendCanonical(dst, src []byte) ([]byte, bool) {
+	return AppendCanonicalString(dst, *(*string)(unsafe.Pointer(&src)))
+}
+
+// AppendCanonicalString is exactly like AppendCanonical, but for strings.
+func AppendCanonicalString(dst []byte, src string) ([]byte, bool) {
+	c := canonicalizer{
+		p: parser{
+			in:         src,
+			strictUTF8: true,
+			surrogates: true,
+		},
+		dst: dst,
+	}
+	at, ok := c.value(0)
+	if !ok || skipSpace(src, at) != len(src) {
+		return nil, false
+	}
+	return c.dst, true
+}
+
+// canonicalizer validates and canonicalizes in one pass. Object members are
+// canonicalized into dst in input order and then sorted in place.
+type canonicalizer struct {
+	p   parser // validates strings
+	dst []byte
+
+	scratch []byte    // for unescaping and sorting
+	members []keySpan // members of all objects being canonicalized
+
+	// For sorting members without allocating.
+	sortDst     string
+	sortMembers []keySpan
+}
+
+func (c *canonicalizer) value(at int) (int, bool) {
+	in := c.p.in
+	if at = skipSpace(in, at); at == len(in) {
+		return at, false
+	}
+	switch in[at] {
+	case '{':
+		return c.obj(at)
+	case '[':
+		return c.arr(at)
+	case '"':
+		return c.str(at)
+	case 't':
+		return c.lit(at, "true")
+	case 'f':
+		return c.lit(at, "false")
+	case 'n':
+		return c.lit(at, "null")
+	case '-', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
+		return c.num(at)
+	}
+	return at, false
+}
+
+func (c *canonicalizer) obj(at int) (int, bool) {
+	in := c.p.in
+	c.dst = append(c.dst, '{')
+	if at = skipSpace(in, at+1); at < len(in) && in[at] == '}' {
+		c.dst = append(c.dst, '}')
+		return at + 1, true
+	}
+
+	base := len(c.members)
+
+	var ok bool
+	for {
+		if at == len(in) || in[at] != '"' {
+			return at, false
+		}
+		span := keySpan{start: len(c.dst)}
+		if at, ok = c.str(at); !ok {
+			return at, false
+		}
+		if at = skipSpace(in, at); at == len(in) || in[at] != ':' {
+			return at, false
+		}
+		span.colon = len(c.dst)
+		c.dst = append(c.dst, ':')
+		if at, ok = c.value(at + 1); !ok {
+			return at, false
+		}
+		span.end = len(c.dst)
+		c.members = append(c.members, span)
+
+		if at = skipSpace(in, at); at == len(in) {
+			return at, false
+		}
+		switch in[at] {
+		case ',':
+			c.dst = append(c.dst, ',')
+			at = skipSpace(in, at+1)
+		case '}':
+			if !c.sort(c.members[base:]) {
+				return at, false
+			}
+			c.members = c.members[:base]
+			c.dst = append(c.dst, '}')
+			return at + 1, true
+		default:
+			return at, false
+		}
+	}
+}
+
+// sort sorts the members of an object, which are the last spans in dst,
+// returning false if any keys are duplicates.
+func (c *canonicalizer) sort(members []keySpan) bool {
+	c.sortDst, c.sortMembers = unsafeString(c.dst), members
+	s := (*canonicalSorter)(c)
+	sorted := sort.IsSorted(s)
+	if !sorted {
+		sort.Sort(s)
+	}
+
+	// Equal keys canonicalize identically, meaning keys are unique if
+	// they are strictly increasing.
+	unique := true
+	for i := 1; i < len(members) && unique; i++ {
+		unique = s.Less(i-1, i)
+	}
+	c.sortDst, c.sortMembers = "", nil
+	if !unique {
+		return false
+	}
+
+	if !sorted {
+		// We copy the members out and write them back in order.
+		start, end := len(c.dst), 0
+		for _, m := range members {
+			if m.start < start {
+				start = m.start
+			}
+			if m.end > end {
+				end = m.end
+			}
+		}
+		c.scratch = append(c.scratch[:0], c.dst[start:end]...)
+		c.dst = c.dst[:start]
+		for i, m := range members {
+			if i > 0 {
+				c.dst = append(c.dst, ',')
+			}
+			c.dst = append(c.dst, c.scratch[m.start-start:m.end-start]...)
+		}
+	}
+	return true
+}
+
+func (c *canonicalizer) arr(at int) (int, bool) {
+	in := c.p.in
+	c.dst = append(c.dst, '[')
+	if at = skipSpace(in, at+1); at < len(in) && in[at] == ']' {
+		c.dst = append(c.dst, ']')
+		return at + 1, true
+	}
+
+	var ok bool
+	for {
+		if at, ok = c.value(at); !ok {
+			return at, false
+		}
+		if at = skipSpace(in, at); at == len(in) {
+			return at, false
+		}
+		switch in[at] {
+		case ',':
+			c.dst = append(c.dst, ',')
+			at++
+		case ']':
+			c.dst = append(c.dst, ']')
+			return at + 1, true
+		default:
+			return at, false
+		}
+	}
+}
+
+func (c *canonicalizer) str(at int) (int, bool) {
+	end, r := c.p.str(at)
+	if r != rOK {
+		return end, false
+	}
+	c.scratch = appendUnescaped(c.scratch[:0], c.p.in[at+1:end-1])
+	c.dst = append(c.dst, '"')
+	c.dst = EscapeString(c.dst, unsafeString(c.scratch), escapeShort)
+	c.dst = append(c.dst, '"')
+	return end, true
+}
+
+func (c *canonicalizer) lit(at int, lit string) (int, bool) {
+	end, ok := stackLit(c.p.in, at, lit)
+	if ok {
+		c.dst = append(c.dst, lit...)
+	}
+	return end, ok
+}
+
+func (c *canonicalizer) num(at int) (int, bool) {
+	start := at
+	at, r := errNum(c.p.in, at)
+	if r != rOK {
+		return at, false
+	}
+	f, _ := strconv.ParseFloat(c.p.in[start:at], 64)
+	if math.IsInf(f, 0) {
+		return start, false
+	}
+	c.dst = appendFloat(c.dst, f, floatES)
+	return at, true
+}
+
+// floatFormat is how appendFloat writes numbers.
+type floatFormat uint8
+
+const (
+	// floatES is how ECMAScript's Number.prototype.toString writes
+	// numbers, as JCS requires: without an exponent if the exponent is in
+	// [-7, 21), and otherwise as d.ddde+dd.
+	floatES floatFormat = iota
+
+	// floatShortest is the shortest way to write numbers, as used by
+	// NumbersShortest: with or without an exponent, whichever is shorter,
+	// where the exponent follows the digits written as an integer.
+	floatShortest
+)
+
+// appendFloat appends the finite f with the fewest digits that parse back to
+// f, placing the decimal point or writing an exponent as format says.
+func appendFloat(dst []byte, f float64, format floatFormat) []byte {
+	if f == 0 { // also -0
+		return append(dst, '0')
+	}
+	if f < 0 {
+		dst = append(dst, '-')
+		f = -f
+	}
+
+	// We format as d.ddde+dd, then pull out the digits and exponent.
+	var buf [32]byte
+	e := strconv.AppendFloat(buf[:0], f, 'e', -1, 64)
+	ei := len(e) - 1
+	for e[ei] != 'e' {
+		ei--
+	}
+	exp := 0
+	for _, c := range e[ei+2:] {
+		exp = exp*10 + int(c-'0')
+	}
+	if e[ei+1] == '-' {
+		exp = -exp
+	}
+	digits := e[:1]
+	if ei > 1 { // drop the dot
+		digits = e[:copy(e[1:], e[2:ei])+1]
+	}
+
+	// The value is 0.digits * 10^n.
+	n := exp + 1
+	switch format {
+	case floatES:
+		if -6 < n && n <= 21 {
+			return appendPlainFloat(dst, digits, n)
+		}
+	case floatShortest:
+		// Without an exponent, we need the digits and either trailing
+		// zeros or a dot and leading zeros. With one, we write the
+		// digits as an integer followed by the exponent.
+		k := len(digits)
+		var plain int
+		switch {
+		case n >= k:
+			plain = n
+		case n > 0:
+			plain = k + 1
+		default:
+			plain = 2 - n + k
+		}
+		sciExp := n - k
+		if plain <= k+1+intLen(sciExp) {
+			return appendPlainFloat(dst, digits, n)
+		}
+		dst = append(dst, digits...)
+		dst = append(dst, 'e')
+		return strconv.AppendInt(dst, int64(sciExp), 10)
+	}
+
+	// Otherwise, we write d.ddde+dd.
+	dst = append(dst, digits[0])
+	if len(digits) > 1 {
+		dst = append(dst, '.')
+		dst = append(dst, digits[1:]...)
+	}
+	dst = append(dst, 'e')
+	if exp >= 0 {
+		dst = append(dst, '+')
+	}
+	return strconv.AppendInt(dst, int64(exp), 10)
+}
+
+// appendPlainFloat appends 0.digits * 10^n without an exponent.
+func appendPlainFloat(dst, digits []byte, n int) []byte {
+	k := len(digits)
+	switch {
+	case n >= k:
+		dst = append(dst, digits...)
+		for i := k; i < n; i++ {
+			dst = append(dst, '0')
+		}
+	case n > 0:
+		dst = append(dst, digits[:n]...)
+		dst = append(dst, '.')
+		dst = append(dst, digits[n:]...)
+	default:
+		dst = append(dst, '0', '.')
+		for i := n; i < 0; i++ {
+			dst = append(dst, '0')
+		}
+		dst = append(dst, digits...)
+	}
+	return dst
+}
+
+// canonicalSorter sorts a canonicalizer's sortMembers by key.
+type canonicalSorter canonicalizer
+
+func (s *canonicalSorter) Len() int { return len(s.sortMembers) }
+func (s *canonicalSorter) Swap(i, j int) {
+	s.sortMembers[i], s.sortMembers[j] = s.sortMembers[j], s.sortMembers[i]
+}
+func (s *canonicalSorter) Less(i, j int) bool {
+	mi, mj := s.sortMembers[i], s.sortMembers[j]
+	return lessUTF16(s.sortDst[mi.start+1:mi.colon-1], s.sortDst[mj.start+1:mj.colon-1])
+}
+
+// lessUTF16 returns whether the canonically escaped string body a sorts
+// before b when both are unescaped and compared by UTF-16 code units.
+func lessUTF16(a, b string) bool {
+	var ai, bi int
+	for ai < len(a) && bi < len(b) {
+		var ar, br rune
+		ar, ai = canonicalRune(a, ai)
+		br, bi = canonicalRune(b, bi)
+		if ar == br {
+			continue
+		}
+		// Runes beyond the basic multilingual plane are encoded with
+		// surrogates, which sort before U+E000 through U+FFFF.
+		if ar >= 0xe000 && br >= 0xe000 && (ar >= 0x10000) != (br >= 0x10000) {
+			return ar >= 0x10000
+		}
+		return ar < br
+	}
+	return len(a)-ai < len(b)-bi
+}
+
+// canonicalRune returns the rune beginning at s[i] in a canonically escaped
+// string body, and the offset of the next rune.
+func canonicalRune(s string, i int) (rune, int) {
+	if s[i] == '\\' {
+		// Canonical escapes are only ever of ASCII.
+		var buf [utf8.UTFMax]byte
+		_, next := unescapeUnit(s, i, &buf)
+		return rune(buf[0]), next
+	}
+	r, size := utf8.DecodeRuneInString(s[i:])
+	return r, i + size
+}
@@ -0,0 +1,112 @@
+package chkjson
+
+import (
+	"math"
+	"strconv"
+	"testing"
+)
+
+func TestAppendCanonical(t *testing.T) {
+	for _, test := range []struct {
+		in  string
+		exp string // empty if invalid
+	}{
+		// RFC 8785 section 3.2.2
+		{
+			`{
+  "numbers": [333333333.33333329, 1E30, 4.50, 2e-3, 0.000000000000000000000000001],
+  "string": "\u20ac$\u000F\u000aA'\u0042\u0022\u005c\\\"\/",
+  "literals": [null, true, false]
+}`,
+			`{"literals":[null,true,false],"numbers":[333333333.3333333,1e+30,4.5,0.002,1e-27],"string":"` + "\xe2\x82\xac" + `$\u000f\nA'B\"\\\\\"/"}`,
+		},
+
+		// RFC 8785 section 3.2.3
+		{
+			`{
+  "\u20ac": "Euro Sign",
+  "\r": "Carriage Return",
+  "\ufb33": "Hebrew Letter Dalet With Dagesh",
+  "1": "One",
+  "\ud83d\ude00": "Emoji: Grinning Face",
+  "\u0080": "Control",
+  "\u00f6": "Latin Small Letter O With Diaeresis"
+}`,
+			`{"\r":"Carriage Return","1":"One","` + "\xc2\x80" + `":"Control","` + "\xc3\xb6" + `":"Latin Small Letter O With Diaeresis","` +
+				"\xe2\x82\xac" + `":"Euro Sign","` + "\xf0\x9f\x98\x80" + `":"Emoji: Grinning Face","` + "\xef\xac\xb3" + `":"Hebrew Letter Dalet With Dagesh"}`,
+		},
+
+		{` [ ] `, `[]`},
+		{`{ }`, `{}`},
+		{`"\b\f\u0008\u000C\u001f\/"`, `"\b\f\b\f\u001f/"`},
+		{`{"b":{"d":1,"c":2},"a":[{"z":1,"y":2}]}`, `{"a":[{"y":2,"z":1}],"b":{"c":2,"d":1}}`},
+		{`{"a":1,"b":2}`, `{"a":1,"b":2}`},
+		{`{"aa":1,"a":2}`, `{"a":2,"aa":1}`},
+		{`{"\ue000":1,"\ud800\udc00":2}`, `{"` + "\xf0\x90\x80\x80" + `":2,"` + "\xee\x80\x80" + `":1}`},
+		{`-0`, `0`},
+		{`-0.0e7`, `0`},
+		{`1e-400`, `0`},
+		{`100e-2`, `1`},
+		{`123456789012345678901234`, `1.2345678901234569e+23`},
+
+		// rejected
+		{`{"a":1,"a":2}`, ``},
+		{`{"a":1,"\u0061":2}`, ``},
+		{`{"b":1,"a":2,"b":3}`, ``},
+		{`1e400`, ``},
+		{`-1e400`, ``},
+		{`"\ud800"`, ``},
+		{"\"\xff\"", ``},
+		{`[1,]`, ``},
+		{`{"a":1}}`, ``},
+		{`{"a" 1}`, ``},
+	} {
+		got, ok := AppendCanonicalString(nil, test.in)
+		if ok != (test.exp != "") || string(got) != test.exp {
+			t.Errorf("«%s»: got «%s» (ok? %v) != exp «%s»", test.in, got, ok, test.exp)
+		}
+		gotb, okb := AppendCanonical([]byte("x"), []byte(test.in))
+		if okb != ok || ok && string(gotb) != "x"+string(got) {
+			t.Errorf("«%s»: got «%s» (ok? %v) as bytes != «%s» (ok? %v)", test.in, gotb, okb, got, ok)
+		}
+	}
+}
+
+func TestAppendCanonicalNumbers(t *testing.T) {
+	// RFC 8785 appendix B
+	for _, test := range []struct {
+		bits uint64
+		exp  string
+	}{
+		{0x0000000000000000, "0"},
+		{0x8000000000000000, "0"},
+		{0x0000000000000001, "5e-324"},
+		{0x8000000000000001, "-5e-324"},
+		{0x7fefffffffffffff, "1.7976931348623157e+308"},
+		{0xffefffffffffffff, "-1.7976931348623157e+308"},
+		{0x4340000000000000, "9007199254740992"},
+		{0xc340000000000000, "-9007199254740992"},
+		{0x4430000000000000, "295147905179352830000"},
+		{0x44b52d02c7e14af5, "9.999999999999997e+22"},
+		{0x44b52d02c7e14af6, "1e+23"},
+		{0x44b52d02c7e14af7, "1.0000000000000001e+23"},
+		{0x444b1ae4d6e2ef4e, "999999999999999700000"},
+		{0x444b1ae4d6e2ef4f, "999999999999999900000"},
+		{0x444b1ae4d6e2ef50, "1e+21"},
+		{0x3eb0c6f7a0b5ed8c, "9.999999999999997e-7"},
+		{0x3eb0c6f7a0b5ed8d, "0.000001"},
+		{0x41b3de4355555553, "333333333.3333332"},
+		{0x41b3de4355555554, "333333333.33333325"},
+		{0x41b3de4355555555, "333333333.3333333"},
+		{0x41b3de4355555556, "333333333.3333334"},
+		{0x41b3de4355555557, "333333333.33333343"},
+		{0xbecbf647612f3696, "-0.0000033333333333333333"},
+		{0x43143ff3c1cb0959, "1424953923781206.2"},
+	} {
+		in := strconv.FormatFloat(math.Float64frombits(test.bits), 'g', -1, 64)
+		got, ok := AppendCanonicalString(nil, in)
+		if !ok || string(got) != test.exp {
+			t.Errorf("%016x (%s): got %s (ok? %v) != exp %s", test.bits, in, got, ok, test.exp)
+		}
+	}
+}
@@ -18,6 +18,11 @@
 // a direct Compact function that is faster and more intuitive than using
 // AppendCompact to compact in place.
 //
+// When a bool is not enough, ValidErr and Options provide slower validating
+// and compacting that return errors describing where and why input was
+// rejected. Options further allows for stricter validation, such as limiting
+// how deeply input can nest.
+//
 // In essence, this library aims to provide faster and allocation free
 // alternatives to encoding/json for a few specific use cases. For use cases
 // and design considerations, visit this project's repo's README.
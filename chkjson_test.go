@@ -28,6 +28,9 @@ func TestMost(t *testing.T) {
 		"\"\xe2\x79\"", // begin line-sep but not actually line sep
 		`"foo"`,
 		"\"\xe2\x80\xa8\xe2\x80\xa9\"", // line-sep and paragraph-sep
+		"\"\xe2\x80\xa8a\xe2\x80\"",    // line-sep, then a truncated one
+		` "<a href=\"x\">&amp;</a>" `,
+		` "\\<\\u003c" `,
 		` "\uaaaa" `,
 		` "\uaaaa\uaaaa" `,
 		` "\`,
@@ -74,6 +77,7 @@ func TestMost(t *testing.T) {
 		`{{"foo": [{"":3, 4: "3"}, 4, "5": {4}]}, "t_wo": 1}`,
 		`{"\uaaaa\uaaaa" : true}`,
 		"{\"\xe2\x80\xa8\xe2\x80\xa9\": true}", // line-sep and paragraph-sep
+		`{"<": [">", {"&": "&&"}]}`,
 		"{",
 		`{"foo"`,
 		`{"foo",f}`,
@@ -130,6 +134,9 @@ func TestMost(t *testing.T) {
 			if val != expVal {
 				t.Errorf("«%s»: got valid? %v, truth? %v", test, val, expVal)
 			}
+			if err := ValidErr(in); (err == nil) != expVal {
+				t.Errorf("«%s»: got valid err %v, truth? %v", test, err, expVal)
+			}
 
 			pact, pactVal := AppendCompact(nil, in)
 			pactStr, pactValStr := AppendCompactString(nil, test)
@@ -144,6 +151,14 @@ func TestMost(t *testing.T) {
 				t.Errorf("«%s»: compact got valid? %v, valid as string? %v", test, pactVal, pactValStr)
 			}
 
+			optsPact, optsErr := new(Options).AppendCompact(nil, in)
+			if (optsErr == nil) != expVal {
+				t.Errorf("«%s»: options compact got err %v, truth? %v", test, optsErr, expVal)
+			}
+			if !bytes.Equal(optsPact, pact) {
+				t.Errorf("«%s»: options compact got «%s» != AppendCompact «%s»", test, optsPact, pact)
+			}
+
 			pactInplace, pactInplaceOk := Compact(append([]byte(nil), in...))
 			if pactInplaceOk != expVal {
 				t.Errorf("«%s»: compact inplace got valid? %v, truth? %v", test, pactInplaceOk, expVal)
@@ -152,6 +167,14 @@ func TestMost(t *testing.T) {
 				t.Errorf("«%s»: compact inplace got «%s» != AppendCompact as inplace «%s»", test, pactInplace, pact)
 			}
 
+			jsonp, jsonpVal := AppendCompactJSONP(nil, in)
+			jsonpStr, jsonpValStr := AppendCompactJSONPString(nil, test)
+			jsonpInplace, jsonpInplaceVal := AppendCompactJSONP(origBytes[:0], origBytes)
+			if jsonpVal != expVal || jsonpValStr != expVal || jsonpInplaceVal != expVal {
+				t.Errorf("«%s»: compact jsonp got valid? %v, valid as string? %v, valid inplace? %v, truth? %v",
+					test, jsonpVal, jsonpValStr, jsonpInplaceVal, expVal)
+			}
+
 			if !expVal {
 				return
 			}
@@ -168,6 +191,22 @@ func TestMost(t *testing.T) {
 			if !bytes.Equal(pactStr, expPact) {
 				t.Errorf("«%s»: compact string got «%s», exp «%s»", test, pactStr, expPact)
 			}
+
+			// Modern encoding/json no longer escapes in Compact, but
+			// HTMLEscape over compacted JSON is what Compact used to do.
+			buf = new(bytes.Buffer)
+			json.HTMLEscape(buf, expPact)
+			expJSONP := buf.Bytes()
+
+			if !bytes.Equal(jsonp, expJSONP) {
+				t.Errorf("«%s»: compact jsonp got «%s», exp «%s»", test, jsonp, expJSONP)
+			}
+			if !bytes.Equal(jsonpStr, expJSONP) {
+				t.Errorf("«%s»: compact jsonp string got «%s», exp «%s»", test, jsonpStr, expJSONP)
+			}
+			if !bytes.Equal(jsonpInplace, expJSONP) {
+				t.Errorf("«%s»: compact jsonp inplace got «%s», exp «%s»", test, jsonpInplace, expJSONP)
+			}
 		})
 	}
 }
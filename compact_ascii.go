@@ -0,0 +1,84 @@
+package chkjson
+
+import (
+	"unicode/utf8"
+	"unsafe"
+)
+
+// AppendCompactASCII is like AppendCompact, but also escapes every non-ASCII
+// character in strings exactly as Escape does with EscapeASCII, meaning the
+// output is entirely 7-bit ASCII. Invalid UTF-8 is escaped as \ufffd.
+//
+// This function assumes and returns ownership of dst. If src is invalid, this
+// will return nil.
+//
+// As with AppendCompactJSONP, escaping can make the output longer than the
+// input: src is first compacted and then grown and escaped from the back. It
+// is valid to pass (src[:0], src) to this function; the input slice is only
+// reallocated if its capacity cannot hold the escaped output.
+func AppendCompactASCII(dst, src []byte) ([]byte, bool) {
+	return AppendCompactASCIIString(dst, *(*string)(unsafe.Pointer(&src)))
+}
+
+// AppendCompactASCIIString is exactly like AppendCompactASCII but for
+// compacting strings.
+func AppendCompactASCIIString(dst []byte, src string) ([]byte, bool) {
+	start := len(dst)
+	dst, ok := AppendCompactString(dst, src)
+	if !ok {
+		return nil, false
+	}
+	return expandASCII(dst, start), true
+}
+
+// expandASCII escapes all non-ASCII characters in b[start:], which must be
+// compact and valid JSON.
+//
+// As with expandJSONP, non-ASCII bytes can only exist within strings, meaning
+// we do not need to track where strings begin and end.
+func expandASCII(b []byte, start int) []byte {
+	grow := 0
+	for i := start; i < len(b); {
+		if b[i] < utf8.RuneSelf {
+			i++
+			continue
+		}
+		r, size := utf8.DecodeRune(b[i:])
+		switch {
+		case r == utf8.RuneError && size == 1:
+			grow += 5 // invalid byte to \ufffd
+		case r >= 0x10000:
+			grow += 12 - size // surrogate pair
+		default:
+			grow += 6 - size
+		}
+		i += size
+	}
+	if grow == 0 {
+		return b
+	}
+
+	r := len(b)
+	b = append(b, make([]byte, grow)...)
+
+	// We walk backwards exactly as in expandJSONP. Decoding UTF-8
+	// backwards splits runes and invalid bytes exactly as decoding
+	// forwards does.
+	var esc [12]byte
+	w := len(b)
+	for i := r; i < w; {
+		c := b[i-1]
+		if c < utf8.RuneSelf {
+			i--
+			w--
+			b[w] = c
+			continue
+		}
+		u, size := utf8.DecodeLastRune(b[start:i])
+		e := appendEscapedRune(esc[:0], u)
+		i -= size
+		w -= len(e)
+		copy(b[w:], e)
+	}
+	return b
+}
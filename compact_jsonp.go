@@ -0,0 +1,84 @@
+package chkjson
+
+import (
+	"unsafe"
+)
+
+// AppendCompactJSONP is like AppendCompact, but also escapes the line
+// separator and paragraph separator characters (U+2028 and U+2029) and the
+// HTML characters <, >, and &. The output is byte for byte what encoding/json
+// produces when running HTMLEscape over Compact's output, which is what older
+// versions of encoding/json's Compact produced directly.
+//
+// This function assumes and returns ownership of dst. If src is invalid, this
+// will return nil.
+//
+// It is valid to pass (src[:0], src) to this function. Because escaping can
+// make the output longer than the input, compacting in place works in two
+// steps: src is first compacted in place exactly as with AppendCompact, and
+// then the compacted JSON is grown by exactly the number of bytes the escapes
+// need and the escapes are expanded from the back. The input slice is only
+// reallocated if its capacity cannot hold the escaped output.
+func AppendCompactJSONP(dst, src []byte) ([]byte, bool) {
+	return AppendCompactJSONPString(dst, *(*string)(unsafe.Pointer(&src)))
+}
+
+// AppendCompactJSONPString is exactly like AppendCompactJSONP but for
+// compacting strings.
+func AppendCompactJSONPString(dst []byte, src string) ([]byte, bool) {
+	start := len(dst)
+	dst, ok := AppendCompactString(dst, src)
+	if !ok {
+		return nil, false
+	}
+	return expandJSONP(dst, start), true
+}
+
+// expandJSONP escapes all JSONP and HTML characters in b[start:], which must
+// be compact and valid JSON.
+//
+// In compacted JSON, these characters can only exist within strings and can
+// never be part of an existing escape sequence, meaning we can look at bytes
+// directly without tracking where strings begin and end.
+func expandJSONP(b []byte, start int) []byte {
+	const hex = "0123456789abcdef"
+
+	grow := 0
+	for i := start; i < len(b); i++ {
+		switch b[i] {
+		case '<', '>', '&':
+			grow += 5 // < to \u003c
+		case 0xe2:
+			if i+2 < len(b) && b[i+1] == 0x80 && b[i+2]&^1 == 0xa8 {
+				grow += 3 // 3 byte UTF-8 to \u2028
+				i += 2
+			}
+		}
+	}
+	if grow == 0 {
+		return b
+	}
+
+	r := len(b)
+	b = append(b, make([]byte, grow)...)
+
+	// We walk backwards, moving bytes to the end of the grown slice and
+	// expanding escapes as we go. Once the write position catches up to
+	// the read position, everything before is already in place.
+	w := len(b)
+	for i := r - 1; i+1 < w; i-- {
+		switch c := b[i]; {
+		case c == '<' || c == '>' || c == '&':
+			w -= 6
+			b[w], b[w+1], b[w+2], b[w+3], b[w+4], b[w+5] = '\\', 'u', '0', '0', hex[c>>4], hex[c&0xf]
+		case c&^1 == 0xa8 && i-2 >= start && b[i-1] == 0x80 && b[i-2] == 0xe2:
+			w -= 6
+			b[w], b[w+1], b[w+2], b[w+3], b[w+4], b[w+5] = '\\', 'u', '2', '0', '2', hex[c&0xf]
+			i -= 2
+		default:
+			w--
+			b[w] = c
+		}
+	}
+	return b
+}
@@ -3,6 +3,7 @@ package chkjson
 import (
 	"bytes"
 	"encoding/json"
+	"reflect"
 	"testing"
 	"unsafe"
 )
@@ -65,6 +66,42 @@ func TestCompact(t *testing.T) {
 	}
 }
 
+func TestCompactJSONPInplace(t *testing.T) {
+	in := "[ \"<\u2028>\" , \"\xe2\x80\xa9&\" ]"
+	exp := `["\u003c\u2028\u003e","\u2029\u0026"]`
+
+	// With enough capacity, escaping in place must not reallocate.
+	buf := make([]byte, len(in), len(exp))
+	copy(buf, in)
+	addr := uintptr(unsafe.Pointer(&buf[0]))
+	got, ok := AppendCompactJSONP(buf[:0], buf)
+	if !ok {
+		t.Fatal("AppendCompactJSONP on known good json is invalid")
+	}
+	if string(got) != exp {
+		t.Errorf("got %s != exp %s", got, exp)
+	}
+	if uintptr(unsafe.Pointer(&got[0])) != addr {
+		t.Error("AppendCompactJSONP with enough capacity changed addresses")
+	}
+
+	// Without enough capacity, we grow and still escape properly.
+	buf = []byte(in)
+	got, ok = AppendCompactJSONP(buf[:0], buf)
+	if !ok {
+		t.Fatal("AppendCompactJSONP on known good json is invalid")
+	}
+	if string(got) != exp {
+		t.Errorf("got %s != exp %s", got, exp)
+	}
+
+	// Appending to a non-empty dst only escapes what was appended.
+	got, ok = AppendCompactJSONPString([]byte("<"), in)
+	if !ok || string(got) != "<"+exp {
+		t.Errorf("got %s (ok? %v) != exp <%s", got, ok, exp)
+	}
+}
+
 func BenchmarkCompact(b *testing.B) {
 	orig := []byte(`{"foo": 1, "bar": [{"fi\uabcdrst": 1,  "se\\cond": 2, "last": 9999}, {}]}`)
 	a := make([]byte, 0, len(orig))
@@ -86,3 +123,45 @@ func BenchmarkCompactInplace(b *testing.B) {
 		}
 	})
 }
+
+func TestCompactASCII(t *testing.T) {
+	for _, test := range []struct {
+		in  string
+		exp string // empty if invalid
+	}{
+		{`1`, `1`},
+		{` { "a" : "b" } `, `{"a":"b"}`},
+		{"{\"\xc3\xa9\": [\"x\xe2\x80\xa8y\", \"\xf0\x9f\x98\x80\"]}", `{"\u00e9":["x\u2028y","\ud83d\ude00"]}`},
+		{"\"\xff\xe2\x80\"", `"\ufffd\ufffd\ufffd"`},
+		{"\"\xef\xbf\xbd<\x7f\"", `"\ufffd<` + "\x7f\""},
+		{"[\"\xc3\xa9\"", ``},
+	} {
+		got, ok := AppendCompactASCIIString(nil, test.in)
+		if ok != (test.exp != "") || string(got) != test.exp {
+			t.Errorf("«%s»: got «%s» (ok? %v) != exp «%s»", test.in, got, ok, test.exp)
+		}
+		in := []byte(test.in)
+		got, ok = AppendCompactASCII(in[:0], in)
+		if ok != (test.exp != "") || string(got) != test.exp {
+			t.Errorf("«%s»: got inplace «%s» (ok? %v) != exp «%s»", test.in, got, ok, test.exp)
+		}
+	}
+
+	for i := 0; i < 3; i++ {
+		big := genBig()
+		got, ok := AppendCompactASCII(nil, big)
+		if !ok {
+			t.Fatal("AppendCompactASCII on known good json is invalid")
+		}
+		for _, c := range got {
+			if c >= 0x80 {
+				t.Fatalf("AppendCompactASCII output contains non-ASCII byte %x", c)
+			}
+		}
+		var exp, gotv interface{}
+		json.Unmarshal(big, &exp)
+		if err := json.Unmarshal(got, &gotv); err != nil || !reflect.DeepEqual(gotv, exp) {
+			t.Errorf("AppendCompactASCII output unmarshals differently (err %v)", err)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package chkjson
+
+import (
+	"unsafe"
+)
+
+// AppendCompactDropNulls is like AppendCompact, but also drops object members
+// whose value is null: {"a": null, "b": 1} becomes {"b":1}. Commas are only
+// written between members that are kept, meaning an object with nothing but
+// null members becomes {}.
+//
+// If recursive is false, only the members of src's outermost object are
+// dropped; otherwise, members are dropped from every object, including objects
+// within arrays. Null array elements are always kept, as their position is
+// meaningful.
+//
+// This function assumes and returns ownership of dst. If src is invalid, this
+// will return nil. As dropping only shrinks the output, it is valid to pass
+// (src[:0], src) to this function to compact in place.
+func AppendCompactDropNulls(dst, src []byte, recursive bool) ([]byte, bool) {
+	return AppendCompactDropNullsString(dst, *(*string)(unsafe.Pointer(&src)), recursive)
+}
+
+// AppendCompactDropNullsString is exactly like AppendCompactDropNulls, but for
+// compacting strings.
+func AppendCompactDropNullsString(dst []byte, src string, recursive bool) ([]byte, bool) {
+	d := nullDropper{walker: walker{in: src, dst: dst}, recursive: recursive}
+	at, ok := d.value(0, true)
+	if !ok || skipSpace(src, at) != len(src) {
+		return nil, false
+	}
+	return d.dst, true
+}
+
+// CompactDropNulls is exactly like AppendCompactDropNulls, but compacts b in
+// place.
+func CompactDropNulls(b []byte, recursive bool) ([]byte, bool) {
+	return AppendCompactDropNulls(b[:0], b, recursive)
+}
+
+// nullDropper compacts like packAny, but drops null object members.
+type nullDropper struct {
+	walker
+	recursive bool
+}
+
+func (d *nullDropper) value(at int, drop bool) (int, bool) {
+	in := d.in
+	if at = skipSpace(in, at); at == len(in) {
+		return at, false
+	}
+	var ok bool
+	switch in[at] {
+	case '{':
+		return d.obj(at, drop)
+	case '[':
+		return d.walkArr(at, d.elem)
+	default:
+		d.dst, at, ok = packAny(d.dst, in, at)
+		return at, ok
+	}
+}
+
+func (d *nullDropper) obj(at int, drop bool) (int, bool) {
+	return d.walkObj(at, func(_ string, at int) (int, bool, bool) {
+		d.dst = append(d.dst, ':')
+		at = skipSpace(d.in, at)
+		null := at < len(d.in) && d.in[at] == 'n'
+		at, ok := d.value(at, d.recursive)
+		return at, !drop || !null, ok
+	})
+}
+
+// elem keeps every array element, as positions in arrays are meaningful.
+func (d *nullDropper) elem(at int) (int, bool, bool) {
+	at, ok := d.value(at, d.recursive)
+	return at, true, ok
+}
@@ -0,0 +1,64 @@
+package chkjson
+
+import (
+	"testing"
+)
+
+func TestAppendCompactDropNulls(t *testing.T) {
+	for _, test := range []struct {
+		in        string
+		recursive bool
+		exp       string // empty if invalid
+	}{
+		{`null`, false, `null`},
+		{` [ null , 1 ] `, true, `[null,1]`},
+		{`{"a": null}`, false, `{}`},
+		{`{ "a" : null , "b" : 1 , "c" : null }`, false, `{"b":1}`},
+		{`{"a": 1, "b": null, "c": 2}`, false, `{"a":1,"c":2}`},
+		{`{"a": null, "b": null, "c": 2}`, false, `{"c":2}`},
+		{`{"a": "null", "b": [null]}`, false, `{"a":"null","b":[null]}`},
+		{
+			`{"a": null, "b": {"c": null, "d": 1}, "e": [{"f": null}]}`,
+			false,
+			`{"b":{"c":null,"d":1},"e":[{"f":null}]}`,
+		},
+		{
+			`{"a": null, "b": {"c": null, "d": 1}, "e": [{"f": null}]}`,
+			true,
+			`{"b":{"d":1},"e":[{}]}`,
+		},
+		{`[{"a": null}]`, false, `[{"a":null}]`},
+		{`[{"a": null}]`, true, `[{}]`},
+		{`{"a": {"b": null}}`, true, `{"a":{}}`},
+		{`{"a": {"b": null},}`, true, ``},
+		{`[{"a": null},]`, true, ``},
+
+		{`{"a": nul}`, false, ``},
+		{`{"a": null,}`, false, ``},
+		{`{"a": null`, false, ``},
+		{`{"a" null}`, false, ``},
+		{`{"a": null} 1`, false, ``},
+	} {
+		got, ok := AppendCompactDropNullsString(nil, test.in, test.recursive)
+		if ok != (test.exp != "") || string(got) != test.exp {
+			t.Errorf("«%s» (recursive? %v): got «%s» (ok? %v) != exp «%s»", test.in, test.recursive, got, ok, test.exp)
+		}
+		gotb, okb := AppendCompactDropNulls([]byte("x"), []byte(test.in), test.recursive)
+		if okb != ok || ok && string(gotb) != "x"+string(got) {
+			t.Errorf("«%s»: got «%s» (ok? %v) as bytes != «%s» (ok? %v)", test.in, gotb, okb, got, ok)
+		}
+		inplace, oki := CompactDropNulls([]byte(test.in), test.recursive)
+		if oki != ok || ok && string(inplace) != string(got) {
+			t.Errorf("«%s»: got «%s» (ok? %v) in place != «%s» (ok? %v)", test.in, inplace, oki, got, ok)
+		}
+	}
+
+	// genBig never generates nulls, so nothing is dropped.
+	for i := 0; i < 3; i++ {
+		big := genBig()
+		exp, _ := AppendCompact(nil, big)
+		if got, ok := AppendCompactDropNulls(nil, big, true); !ok || string(got) != string(exp) {
+			t.Errorf("genBig: got ok? %v, equal? %v", ok, string(got) == string(exp))
+		}
+	}
+}
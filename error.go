@@ -0,0 +1,218 @@
+package chkjson
+
+import (
+	"strconv"
+	"unsafe"
+)
+
+// ErrorKind classifies why validation failed.
+type ErrorKind uint8
+
+const (
+	// KindSyntax is used for input that is not JSON.
+	KindSyntax ErrorKind = iota
+	// KindDepth is used for JSON that nests objects and arrays deeper than
+	// Options.MaxDepth allows.
+	KindDepth
+	// KindUTF8 is used for strings containing invalid UTF-8 when
+	// Options.StrictUTF8 is set.
+	KindUTF8
+	// KindSurrogate is used for \u escapes of UTF-16 surrogate halves that
+	// are not paired when Options.RejectLoneSurrogates is set.
+	KindSurrogate
+	// KindDuplicateKey is used for objects containing the same key twice
+	// when Options.RejectDuplicateKeys is set.
+	KindDuplicateKey
+	// KindUnsafeInteger is used for integers that an IEEE 754 double
+	// cannot represent exactly when Options.RejectUnsafeIntegers is set.
+	KindUnsafeInteger
+	// KindNumberRange is used for numbers too large in magnitude for an
+	// IEEE 754 double when Options.RejectHugeNumbers is set.
+	KindNumberRange
+)
+
+// Error is the error returned from the error returning validation functions.
+// It describes where and why validation failed.
+type Error struct {
+	// Kind is the class of failure. Anything but KindSyntax means the input
+	// was rejected for an option, not for being invalid JSON.
+	Kind ErrorKind
+
+	// Offset is the byte offset into the input of the byte that caused
+	// validation to fail. If the input ended early, this is the length of
+	// the input.
+	Offset int
+	// Line is the one based line of Offset. Lines are separated by '\n'.
+	Line int
+	// Column is the one based byte column of Offset within Line.
+	Column int
+
+	// Reason describes what the parser expected at Offset, for example
+	// "expected ':' after object key".
+	Reason string
+	// Found describes what the parser found at Offset, for example "'x'"
+	// or "end of input".
+	Found string
+
+	// Snippet contains up to 16 bytes of input on either side of Offset.
+	Snippet string
+}
+
+// Error implements the error interface.
+func (e *Error) Error() string {
+	what := "invalid"
+	if e.Kind != KindSyntax {
+		what = "rejected"
+	}
+	return "chkjson: " + what + " JSON at line " + strconv.Itoa(e.Line) +
+		", column " + strconv.Itoa(e.Column) +
+		" (offset " + strconv.Itoa(e.Offset) + "): " +
+		e.Reason + ", found " + e.Found +
+		" near " + strconv.Quote(e.Snippet)
+}
+
+// reason is why parsing failed; the zero reason means parsing succeeded.
+type reason uint8
+
+const (
+	rOK reason = iota
+	rValue
+	rTrue
+	rFalse
+	rNull
+	rStrEnd
+	rStrChar
+	rEscape
+	rHex
+	rKeyOrObjEnd
+	rKey
+	rColon
+	rObjNext
+	rValueOrArrEnd
+	rArrNext
+	rNegDigit
+	rDotDigit
+	rExpDigit
+	rEnd
+
+	rDepth
+	rUTF8
+	rLoneHigh
+	rLoneLow
+	rDupKey
+	rUnsafeInt
+	rNumRange
+)
+
+var reasons = [...]string{
+	rOK:            "no error",
+	rValue:         "expected value",
+	rTrue:          "expected literal true",
+	rFalse:         "expected literal false",
+	rNull:          "expected literal null",
+	rStrEnd:        `expected closing '"' of string`,
+	rStrChar:       "expected string character, control characters must be escaped",
+	rEscape:        `expected escape character after '\'`,
+	rHex:           `expected four hex digits after '\u'`,
+	rKeyOrObjEnd:   "expected object key or '}'",
+	rKey:           "expected object key after ','",
+	rColon:         "expected ':' after object key",
+	rObjNext:       "expected ',' or '}' after object value",
+	rValueOrArrEnd: "expected value or ']'",
+	rArrNext:       "expected ',' or ']' after array element",
+	rNegDigit:      "expected digit after '-'",
+	rDotDigit:      "expected digit after '.'",
+	rExpDigit:      "expected digit in exponent",
+	rEnd:           "expected end of input after value",
+
+	rDepth: "exceeded maximum nesting depth",
+	rUTF8:  "expected valid UTF-8 in string",
+
+	rLoneHigh: `expected \u escape of low surrogate after high surrogate`,
+	rLoneLow:  `expected high surrogate escape before low surrogate`,
+	rDupKey:   "expected unique object key",
+
+	rUnsafeInt: "expected integer between -(2^53-1) and 2^53-1",
+	rNumRange:  "expected number within the range of an IEEE 754 double",
+}
+
+func (r reason) String() string { return reasons[r] }
+
+func (r reason) kind() ErrorKind {
+	switch r {
+	case rDepth:
+		return KindDepth
+	case rUTF8:
+		return KindUTF8
+	case rLoneHigh, rLoneLow:
+		return KindSurrogate
+	case rDupKey:
+		return KindDuplicateKey
+	case rUnsafeInt:
+		return KindUnsafeInteger
+	case rNumRange:
+		return KindNumberRange
+	default:
+		return KindSyntax
+	}
+}
+
+// newError returns an *Error for parsing failing at in[at] for reason r on
+// the given line and column. The snippet does not include input before from.
+func newError(in string, at int, r reason, line, col, from int) *Error {
+	lo, hi := at-16, at+16
+	if lo < from {
+		lo = from
+	}
+	if hi > len(in) {
+		hi = len(in)
+	}
+
+	var found string
+	if at < len(in) {
+		found = describeByte(in[at])
+	} else {
+		found = "end of input"
+	}
+
+	return &Error{
+		Kind:    r.kind(),
+		Offset:  at,
+		Line:    line,
+		Column:  col,
+		Reason:  r.String(),
+		Found:   found,
+		Snippet: string(append([]byte(nil), in[lo:hi]...)), // do not alias a possibly unsafe string
+	}
+}
+
+func describeByte(c byte) string {
+	const hex = "0123456789abcdef"
+	switch {
+	case c < 0x20:
+		return "control character 0x" + string([]byte{hex[c>>4], hex[c&0xf]})
+	case c < 0x7f:
+		return strconv.QuoteRune(rune(c))
+	default:
+		return "byte 0x" + string([]byte{hex[c>>4], hex[c&0xf]})
+	}
+}
+
+// ValidErr returns nil if b is valid JSON, and otherwise an *Error describing
+// why it is not.
+//
+// This is slower than Valid and should only be used when the reason for
+// invalid JSON matters. A common pattern is to only call ValidErr after Valid
+// returns false.
+func ValidErr(b []byte) error {
+	return ValidStringErr(*(*string)(unsafe.Pointer(&b)))
+}
+
+// ValidStringErr is exactly like ValidErr, but for strings.
+func ValidStringErr(s string) error {
+	p := parser{in: s}
+	if at, r := p.all(); r != rOK {
+		return p.error(at, r)
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package chkjson
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestValidErr(t *testing.T) {
+	for i, test := range []struct {
+		in     string
+		offset int
+		line   int
+		column int
+		reason string
+		found  string
+	}{
+		{"", 0, 1, 1, "expected value", "end of input"},
+		{"   ", 3, 1, 4, "expected value", "end of input"},
+		{" z", 1, 1, 2, "expected value", "'z'"},
+		{" 1  1", 4, 1, 5, "expected end of input after value", "'1'"},
+		{"00", 1, 1, 2, "expected end of input after value", "'0'"},
+
+		{"tru", 3, 1, 4, "expected literal true", "end of input"},
+		{"trUe", 2, 1, 3, "expected literal true", "'U'"},
+		{"fals", 4, 1, 5, "expected literal false", "end of input"},
+		{"nulL", 3, 1, 4, "expected literal null", "'L'"},
+
+		{`"foo`, 4, 1, 5, `expected closing '"' of string`, "end of input"},
+		{"\"f\x00o\"", 2, 1, 3, "expected string character, control characters must be escaped", "control character 0x00"},
+		{`"\`, 2, 1, 3, `expected escape character after '\'`, "end of input"},
+		{`"\z"`, 2, 1, 3, `expected escape character after '\'`, "'z'"},
+		{`"\uazaa"`, 4, 1, 5, `expected four hex digits after '\u'`, "'z'"},
+		{`"\uaa`, 5, 1, 6, `expected four hex digits after '\u'`, "end of input"},
+
+		{"-", 1, 1, 2, "expected digit after '-'", "end of input"},
+		{"-z", 1, 1, 2, "expected digit after '-'", "'z'"},
+		{"1.", 2, 1, 3, "expected digit after '.'", "end of input"},
+		{"1.e3", 2, 1, 3, "expected digit after '.'", "'e'"},
+		{"1e", 2, 1, 3, "expected digit in exponent", "end of input"},
+		{"1e+", 3, 1, 4, "expected digit in exponent", "end of input"},
+		{"1e+z", 3, 1, 4, "expected digit in exponent", "'z'"},
+
+		{"{", 1, 1, 2, "expected object key or '}'", "end of input"},
+		{"{1", 1, 1, 2, "expected object key or '}'", "'1'"},
+		{`{"foo"`, 6, 1, 7, "expected ':' after object key", "end of input"},
+		{"{\n  \"foo\" 1}", 10, 2, 9, "expected ':' after object key", "'1'"},
+		{`{"foo":`, 7, 1, 8, "expected value", "end of input"},
+		{`{"foo":1`, 8, 1, 9, "expected ',' or '}' after object value", "end of input"},
+		{`{"foo":1]`, 8, 1, 9, "expected ',' or '}' after object value", "']'"},
+		{`{"foo":1,`, 9, 1, 10, "expected object key after ','", "end of input"},
+		{`{"foo":1,}`, 9, 1, 10, "expected object key after ','", "'}'"},
+
+		{"[", 1, 1, 2, "expected value or ']'", "end of input"},
+		{"[}", 1, 1, 2, "expected value or ']'", "'}'"},
+		{"[1", 2, 1, 3, "expected ',' or ']' after array element", "end of input"},
+		{"[1a]", 2, 1, 3, "expected ',' or ']' after array element", "'a'"},
+		{"[1,", 3, 1, 4, "expected value", "end of input"},
+		{"[1,]", 3, 1, 4, "expected value", "']'"},
+		{"[\n1,\r\n\xff]", 6, 3, 1, "expected value", "byte 0xff"},
+		{"[[[tru]]]", 6, 1, 7, "expected literal true", "']'"},
+	} {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			err := ValidStringErr(test.in)
+			if err == nil {
+				t.Fatalf("«%s»: unexpectedly valid", test.in)
+			}
+			if (ValidErr([]byte(test.in)) == nil) != (err == nil) {
+				t.Errorf("«%s»: ValidErr != ValidStringErr", test.in)
+			}
+			got := err.(*Error)
+			if got.Offset != test.offset || got.Line != test.line || got.Column != test.column {
+				t.Errorf("«%s»: got offset %d, line %d, column %d != exp %d, %d, %d",
+					test.in, got.Offset, got.Line, got.Column, test.offset, test.line, test.column)
+			}
+			if got.Reason != test.reason {
+				t.Errorf("«%s»: got reason %q != exp %q", test.in, got.Reason, test.reason)
+			}
+			if got.Found != test.found {
+				t.Errorf("«%s»: got found %q != exp %q", test.in, got.Found, test.found)
+			}
+		})
+	}
+}
+
+func TestValidErrSnippet(t *testing.T) {
+	in := strings.Repeat(" ", 20) + `{"abc" 1}` + strings.Repeat(" ", 20)
+	err := ValidStringErr(in).(*Error)
+	if exp := strings.Repeat(" ", 9) + `{"abc" 1}` + strings.Repeat(" ", 14); err.Snippet != exp {
+		t.Errorf("got snippet %q != exp %q", err.Snippet, exp)
+	}
+	if exp := `chkjson: invalid JSON at line 1, column 28 (offset 27): expected ':' after object key, found '1' near ` + strconv.Quote(err.Snippet); err.Error() != exp {
+		t.Errorf("got error %q != exp %q", err.Error(), exp)
+	}
+}
@@ -1,6 +1,7 @@
 package chkjson
 
 import (
+	"unicode/utf16"
 	"unicode/utf8"
 	"unsafe"
 )
@@ -19,8 +20,16 @@ const (
 	// EscapeJSONP causes Escape to ensure that the line separator and
 	// paragraph separator unicode characters are safely escaped for JSONP.
 	EscapeJSONP
+	// EscapeASCII causes Escape to ensure that the output is entirely
+	// ASCII by escaping every other character as \uXXXX, using a UTF-16
+	// surrogate pair for characters beyond the basic multilingual plane.
+	EscapeASCII
 )
 
+// escapeShort causes Escape to use the short \b and \f escapes for backspace
+// and form feed rather than \u0008 and \u000c, as canonical JSON requires.
+const escapeShort EscapeOpt = -1
+
 // Escape appends a JSON escaped src to dst.
 //
 // This function takes options to configure additional escaping.
@@ -35,13 +44,17 @@ func Escape(dst, src []byte, opts ...EscapeOpt) []byte {
 // This is the same as Escape, but for strings.
 func EscapeString(dst []byte, src string, opts ...EscapeOpt) []byte {
 	const hex = "0123456789abcdef"
-	var html, jsonp bool
+	var html, jsonp, ascii, short bool
 	for _, opt := range opts {
 		switch opt {
 		case EscapeHTML:
 			html = true
 		case EscapeJSONP:
 			jsonp = true
+		case EscapeASCII:
+			ascii = true
+		case escapeShort:
+			short = true
 		}
 	}
 
@@ -62,6 +75,14 @@ func EscapeString(dst []byte, src string, opts ...EscapeOpt) []byte {
 				dst = append(dst, '\\', 'r')
 			case '\t':
 				dst = append(dst, '\\', 't')
+			case '\b', '\f':
+				if !short {
+					dst = append(dst, '\\', 'u', '0', '0', hex[c>>4], hex[c&0xf])
+				} else if c == '\b' {
+					dst = append(dst, '\\', 'b')
+				} else {
+					dst = append(dst, '\\', 'f')
+				}
 			default:
 				dst = append(dst, '\\', 'u', '0', '0', hex[c>>4], hex[c&0xf])
 			}
@@ -79,6 +100,14 @@ func EscapeString(dst []byte, src string, opts ...EscapeOpt) []byte {
 			continue
 		}
 
+		if ascii {
+			dst = append(dst, src[st:i]...)
+			dst = appendEscapedRune(dst, c)
+			i += sz
+			st = i
+			continue
+		}
+
 		if (c == '\u2028' || c == '\u2029') && jsonp {
 			dst = append(dst, src[st:i]...)
 			dst = append(dst, '\\', 'u', '2', '0', '2', hex[c&0xf])
@@ -91,6 +120,16 @@ func EscapeString(dst []byte, src string, opts ...EscapeOpt) []byte {
 	return append(dst, src[st:]...)
 }
 
+// appendEscapedRune appends r as \uXXXX, or as a surrogate pair of two such
+// escapes if r is beyond the basic multilingual plane.
+func appendEscapedRune(dst []byte, r rune) []byte {
+	if r1, r2 := utf16.EncodeRune(r); r1 != utf8.RuneError {
+		return appendEscapedRune(appendEscapedRune(dst, r1), r2)
+	}
+	const hex = "0123456789abcdef"
+	return append(dst, '\\', 'u', hex[r>>12&0xf], hex[r>>8&0xf], hex[r>>4&0xf], hex[r&0xf])
+}
+
 var safeSet = [utf8.RuneSelf]bool{
 	' ': true, '!': true, '#': true, '$': true, '%': true, '&': true,
 	'\'': true, '(': true, ')': true, '*': true, '+': true, ',': true,
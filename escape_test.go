@@ -34,6 +34,16 @@ func TestEscape(t *testing.T) {
 			[]EscapeOpt{EscapeJSONP, EscapeHTML},
 			"\\ufffdz\\n\\r\\t\\\"\\u0000\\u001f\\u003c\\u003e\\u2028\\u2029\u2030\\u0026",
 		},
+		{
+			"\xffz\n<&>\u00e9\u2028\U0001f600\x7f",
+			[]EscapeOpt{EscapeASCII},
+			"\\ufffdz\\n<&>\\u00e9\\u2028\\ud83d\\ude00\x7f",
+		},
+		{
+			"\xffz\n<&>\u00e9\u2028\U0001f600\x7f",
+			[]EscapeOpt{EscapeASCII, EscapeHTML},
+			"\\ufffdz\\n\\u003c\\u0026\\u003e\\u00e9\\u2028\\ud83d\\ude00\x7f",
+		},
 	}
 
 	for i, test := range tests {
@@ -68,6 +78,7 @@ func benchmarkEscape(b *testing.B, ins string) {
 		{"html", []EscapeOpt{EscapeHTML}},
 		{"jsonp", []EscapeOpt{EscapeJSONP}},
 		{"both", []EscapeOpt{EscapeHTML, EscapeJSONP}},
+		{"ascii", []EscapeOpt{EscapeASCII}},
 	} {
 		b.Run(combo.name, func(b *testing.B) {
 			for i := 0; i < b.N; i++ {
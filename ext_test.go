@@ -1,6 +1,7 @@
 package chkjson
 
 import (
+	"bytes"
 	"fmt"
 	"io/ioutil"
 	"testing"
@@ -59,6 +60,20 @@ func TestExtAppendCompactJSON(t *testing.T) {
 	}
 }
 
+func TestExtAppendCompactJSONP(t *testing.T) {
+	for fname, bs := range extFiles {
+		t.Run(fname, func(t *testing.T) {
+			got, valid := AppendCompactJSONP(nil, bs)
+			if !valid {
+				t.Errorf("%s unexpectedly invalid!", fname)
+			}
+			if !Valid(got) {
+				t.Errorf("%s compacted invalid!", fname)
+			}
+		})
+	}
+}
+
 func BenchmarkExtValid(b *testing.B) {
 	for fname, bs := range extFiles {
 		b.Run(fname, func(b *testing.B) {
@@ -86,6 +101,34 @@ func BenchmarkExtCompact(b *testing.B) {
 	}
 }
 
+func BenchmarkExtCompactJSONP(b *testing.B) {
+	for fname, bs := range extFiles {
+		b.Run(fname, func(b *testing.B) {
+			buf, _ := AppendCompactJSONP(nil, bs)
+			b.ReportAllocs()
+			b.SetBytes(int64(len(bs)))
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				AppendCompactJSONP(buf[:0], bs)
+			}
+		})
+	}
+}
+
+func BenchmarkExtCompactASCII(b *testing.B) {
+	for fname, bs := range extFiles {
+		b.Run(fname, func(b *testing.B) {
+			buf, _ := AppendCompactASCII(nil, bs)
+			b.ReportAllocs()
+			b.SetBytes(int64(len(bs)))
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				AppendCompactASCII(buf[:0], bs)
+			}
+		})
+	}
+}
+
 func BenchmarkExtCompactInplace(b *testing.B) {
 	for fname, bs := range extFiles {
 		b.Run(fname, func(b *testing.B) {
@@ -100,6 +143,79 @@ func BenchmarkExtCompactInplace(b *testing.B) {
 	}
 }
 
+func BenchmarkExtValidStack(b *testing.B) {
+	for fname, bs := range extFiles {
+		b.Run(fname, func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(bs)))
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				ValidStack(bs)
+			}
+		})
+	}
+}
+
+func BenchmarkExtCompactStack(b *testing.B) {
+	for fname, bs := range extFiles {
+		b.Run(fname, func(b *testing.B) {
+			buf, _ := AppendCompactStack(nil, bs)
+			b.ReportAllocs()
+			b.SetBytes(int64(len(bs)))
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				AppendCompactStack(buf[:0], bs)
+			}
+		})
+	}
+}
+
+func BenchmarkExtCompactInplaceStack(b *testing.B) {
+	for fname, bs := range extFiles {
+		b.Run(fname, func(b *testing.B) {
+			buf := make([]byte, len(bs))
+			b.ReportAllocs()
+			b.SetBytes(int64(len(bs)))
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				copy(buf, bs)
+				b.StartTimer()
+				CompactStack(buf)
+			}
+		})
+	}
+}
+
+func BenchmarkExtIndent(b *testing.B) {
+	for fname, bs := range extFiles {
+		b.Run(fname, func(b *testing.B) {
+			buf, _ := AppendIndent(nil, bs, "", "\t")
+			b.ReportAllocs()
+			b.SetBytes(int64(len(bs)))
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				AppendIndent(buf[:0], bs, "", "\t")
+			}
+		})
+	}
+}
+
+func BenchmarkExtValidReader(b *testing.B) {
+	for fname, bs := range extFiles {
+		b.Run(fname, func(b *testing.B) {
+			r := bytes.NewReader(bs)
+			b.ReportAllocs()
+			b.SetBytes(int64(len(bs)))
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				r.Reset(bs)
+				ValidReader(r)
+			}
+		})
+	}
+}
+
 /* if you are curious for comparison, uncomment below.
 func BenchmarkExtValidStdlib(b *testing.B) {
 	for fname, bs := range extFiles {
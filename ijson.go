@@ -0,0 +1,47 @@
+package chkjson
+
+// ijson enforces every rule of I-JSON.
+var ijson = Options{
+	StrictUTF8:           true,
+	RejectLoneSurrogates: true,
+	RejectDuplicateKeys:  true,
+	RejectUnsafeIntegers: true,
+	RejectHugeNumbers:    true,
+}
+
+// ValidIJSON returns nil if b is I-JSON (RFC 7493), and otherwise an *Error
+// describing why it is not.
+//
+// I-JSON is JSON that is safe to exchange with any consumer, notably
+// JavaScript: strings must be valid UTF-8 and must not contain escapes of
+// unpaired surrogate halves, object keys must be unique once unescaped, and
+// integers must be within -(2^53-1) through 2^53-1, and numbers must be
+// within the range of an IEEE 754 double. The *Error's Kind names which rule
+// was broken: KindUTF8, KindSurrogate, KindDuplicateKey, KindUnsafeInteger,
+// or KindNumberRange, or KindSyntax if b is not JSON at all.
+//
+// This is equivalent to validating with Options that enable StrictUTF8,
+// RejectLoneSurrogates, RejectDuplicateKeys, RejectUnsafeIntegers, and
+// RejectHugeNumbers.
+func ValidIJSON(b []byte) error {
+	return ijson.Valid(b)
+}
+
+// ValidIJSONString is exactly like ValidIJSON, but for strings.
+func ValidIJSONString(s string) error {
+	return ijson.ValidString(s)
+}
+
+// AppendCompactIJSON is like AppendCompact, but returns an *Error if src is
+// not I-JSON, as described in ValidIJSON. On error, this returns a nil slice.
+//
+// It is valid to pass (src[:0], src) to this function.
+func AppendCompactIJSON(dst, src []byte) ([]byte, error) {
+	return ijson.AppendCompact(dst, src)
+}
+
+// AppendCompactIJSONString is exactly like AppendCompactIJSON, but for
+// compacting strings.
+func AppendCompactIJSONString(dst []byte, src string) ([]byte, error) {
+	return ijson.AppendCompactString(dst, src)
+}
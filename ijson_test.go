@@ -0,0 +1,66 @@
+package chkjson
+
+import (
+	"testing"
+)
+
+func TestIJSON(t *testing.T) {
+	for _, test := range []struct {
+		in     string
+		kind   ErrorKind
+		offset int // -1 if valid
+	}{
+		{`{"a": [1, -2.5, "x", null, true], "b": {"c": "\ud83d\ude00"}}`, 0, -1},
+		{`9007199254740991`, 0, -1},
+		{`-9007199254740991`, 0, -1},
+		{`[900719925474099]`, 0, -1},
+		{`9007199254740992.5`, 0, -1}, // not an integer
+		{`1e300`, 0, -1},
+		{`-1.7976931348623157e308`, 0, -1},
+		{`1e-400`, 0, -1},
+
+		{`[9007199254740992]`, KindUnsafeInteger, 1},
+		{`{"a": -9007199254740992}`, KindUnsafeInteger, 6},
+		{`[1, 12345678901234567890]`, KindUnsafeInteger, 4},
+		{`[1e400]`, KindNumberRange, 1},
+		{`{"a": -1.8e308}`, KindNumberRange, 6},
+		{"[\"a\xffb\"]", KindUTF8, 3},
+		{`["\udc00"]`, KindSurrogate, 2},
+		{`{"a": 1, "\u0061": 2}`, KindDuplicateKey, 9},
+		{`{"a": 1,}`, KindSyntax, 8},
+	} {
+		chk := func(name string, err error) {
+			if test.offset == -1 {
+				if err != nil {
+					t.Errorf("%s %s: unexpected err %v", name, test.in, err)
+				}
+				return
+			}
+			got, ok := err.(*Error)
+			if !ok {
+				t.Errorf("%s %s: got err %v, exp *Error", name, test.in, err)
+				return
+			}
+			if got.Kind != test.kind || got.Offset != test.offset {
+				t.Errorf("%s %s: got kind %d at %d (%v) != exp kind %d at %d", name, test.in, got.Kind, got.Offset, got, test.kind, test.offset)
+			}
+		}
+
+		chk("ValidIJSON", ValidIJSON([]byte(test.in)))
+		chk("ValidIJSONString", ValidIJSONString(test.in))
+
+		got, err := AppendCompactIJSONString(nil, test.in)
+		chk("AppendCompactIJSONString", err)
+		if exp, _ := AppendCompactString(nil, test.in); err == nil && string(got) != string(exp) {
+			t.Errorf("%s: got compact %s != exp %s", test.in, got, exp)
+		}
+		in := []byte(test.in)
+		_, err = AppendCompactIJSON(in[:0], in)
+		chk("AppendCompactIJSON", err)
+	}
+
+	err := ValidIJSONString(`[9007199254740992]`).(*Error)
+	if exp := "expected integer between -(2^53-1) and 2^53-1"; err.Reason != exp {
+		t.Errorf("got reason %q != exp %q", err.Reason, exp)
+	}
+}
@@ -0,0 +1,347 @@
+package chkjson
+
+import (
+	"unsafe"
+)
+
+// AppendIndent appends the indented form of src to dst if src is entirely
+// JSON, returning the updated dst and whether all of src was valid. If src is
+// invalid, this returns nil.
+//
+// The output matches encoding/json's Indent: each element of an object or
+// array begins on a new line starting with prefix followed by one or more
+// copies of indent according to the nesting depth, empty objects and arrays
+// are kept as {} and [], and object keys are followed by ": ". The first line
+// does not begin with prefix. Leading whitespace in src is dropped, while
+// trailing whitespace is kept.
+//
+// Unlike AppendCompact, indenting grows its input, meaning dst must not share
+// memory with src.
+func AppendIndent(dst, src []byte, prefix, indent string) ([]byte, bool) {
+	return AppendIndentString(dst, *(*string)(unsafe.Pointer(&src)), prefix, indent)
+}
+
+// AppendIndentString is exactly like AppendIndent, but for indenting strings.
+func AppendIndentString(dst []byte, src, prefix, indent string) ([]byte, bool) {
+	dst, at, ok := indentAny(dst, src, 0, prefix, indent, 0)
+	if !ok {
+		return nil, false
+	}
+
+	end := skipSpace(src, at)
+	if end != len(src) {
+		return nil, false
+	}
+	return append(dst, src[at:]...), true
+}
+
+// indentAny is a copy of packAny that writes a new line, prefix, and indent
+// where packAny drops whitespace between the members of objects and the
+// elements of arrays. The contents of the value at src[at] are depth levels
+// deep.
+func indentAny(dst []byte, src string, at int, prefix, indent string, depth int) ([]byte, int, bool) {
+	start := at
+	var c byte
+	var ok bool
+
+whitespace:
+	if at == len(src) {
+		return nil, 0, false
+	}
+
+	switch c, at = src[at], at+1; c {
+	case ' ', '\r', '\t', '\n':
+		start++
+		goto whitespace
+	case '{':
+		start++
+		dst = append(dst, '{')
+		goto finObj
+	case '[':
+		start++
+		dst = append(dst, '[')
+		goto finArr
+	case '"':
+		goto finStr
+	case 't':
+		end := at + len("rue")
+		if end <= len(src) &&
+			src[at+2] == 'e' &&
+			src[at] == 'r' &&
+			src[at+1] == 'u' {
+			dst = append(dst, 't', 'r', 'u', 'e')
+			return dst, end, true
+		}
+		return nil, 0, false
+	case 'f':
+		end := at + len("alse")
+		if end <= len(src) && src[at:end] == "alse" {
+			dst = append(dst, 'f', 'a', 'l', 's', 'e')
+			return dst, end, true
+		}
+		return nil, 0, false
+	case 'n':
+		end := at + len("ull")
+		if end <= len(src) &&
+			src[at] == 'u' &&
+			src[at+1] == 'l' &&
+			src[at+2] == 'l' {
+			dst = append(dst, 'n', 'u', 'l', 'l')
+			return dst, end, true
+		}
+		return nil, 0, false
+	case '-':
+		goto finNeg
+	case '0':
+		goto fin0
+	case '1', '2', '3', '4', '5', '6', '7', '8', '9':
+		goto fin1
+	default:
+		return nil, 0, false
+	}
+
+finStr:
+	for ; at < len(src); at++ {
+		switch src[at] {
+		case 0, 1, 2, 3, 4, 5, 6, 7, 8, 9,
+			10, 11, 12, 13, 14, 15, 16, 17, 18, 19,
+			20, 21, 22, 23, 24, 25, 26, 27, 28, 29,
+			30, 31:
+			return nil, 0, false
+		case '"':
+			at++
+			dst = append(dst, src[start:at]...)
+			return dst, at, true
+		case '\\':
+			at++
+			if at == len(src) {
+				return nil, 0, false
+			}
+			switch src[at] {
+			case 'b', 'f', 'n', 'r', 't', '\\', '/', '"':
+			case 'u':
+				if len(src[at:]) > 5 &&
+					isHex(src[at+1]) &&
+					isHex(src[at+2]) &&
+					isHex(src[at+3]) &&
+					isHex(src[at+4]) {
+					at += 5
+					goto finStr
+				}
+				return nil, 0, false
+			default:
+				return nil, 0, false
+			}
+		}
+	}
+	return nil, 0, false
+
+finObj:
+	for at < len(src) { // finish obj immediately or begin a key
+		switch c, at = src[at], at+1; c {
+		case ' ', '\r', '\t', '\n':
+			start++
+		case '"':
+			dst = indentLine(dst, prefix, indent, depth+1)
+			goto finObjKey
+		case '}':
+			dst = append(dst, '}')
+			return dst, at, true
+		default:
+			return nil, 0, false
+		}
+	}
+
+finObjKey:
+	for ; at < len(src); at++ { // duplicated above for better jumps
+		switch src[at] {
+		case 0, 1, 2, 3, 4, 5, 6, 7, 8, 9,
+			10, 11, 12, 13, 14, 15, 16, 17, 18, 19,
+			20, 21, 22, 23, 24, 25, 26, 27, 28, 29,
+			30, 31:
+			return nil, 0, false
+		case '"':
+			at++
+			dst, start = append(dst, src[start:at]...), at
+			goto finObjSep
+		case '\\':
+			at++
+			if at == len(src) {
+				return nil, 0, false
+			}
+			switch src[at] {
+			case 'b', 'f', 'n', 'r', 't', '\\', '/', '"':
+			case 'u':
+				if len(src[at:]) > 5 &&
+					isHex(src[at+1]) &&
+					isHex(src[at+2]) &&
+					isHex(src[at+3]) &&
+					isHex(src[at+4]) {
+					at += 5
+					goto finObjKey
+				}
+				return nil, 0, false
+			default:
+				return nil, 0, false
+			}
+		}
+	}
+	return nil, 0, false
+
+finObjSep:
+	for at < len(src) {
+		switch c, at = src[at], at+1; c {
+		case ' ', '\r', '\t', '\n':
+		case ':':
+			dst = append(dst, ':', ' ')
+			goto objAny
+		default:
+			return nil, 0, false
+		}
+	}
+
+objAny:
+	if dst, at, ok = indentAny(dst, src, at, prefix, indent, depth+1); !ok {
+		return nil, 0, false
+	}
+
+	for at < len(src) {
+		switch c, at = src[at], at+1; c {
+		case ' ', '\r', '\t', '\n':
+		case ',':
+			dst = append(dst, ',')
+			goto beginStr
+		case '}':
+			dst = indentLine(dst, prefix, indent, depth)
+			dst = append(dst, '}')
+			return dst, at, true
+		default:
+			return nil, 0, false
+		}
+	}
+
+beginStr:
+	for at < len(src) {
+		switch c, at = src[at], at+1; c {
+		case ' ', '\r', '\t', '\n':
+		case '"':
+			dst, start = indentLine(dst, prefix, indent, depth+1), at-1
+			goto finObjKey
+		default:
+			return nil, 0, false
+		}
+	}
+	return nil, 0, false
+
+finArr:
+	for at < len(src) {
+		switch c = src[at]; c {
+		case ' ', '\r', '\t', '\n':
+			at++
+		case ']':
+			dst = append(dst, ']')
+			return dst, at + 1, true
+		default:
+			goto arrAny
+		}
+	}
+
+arrAny:
+	dst = indentLine(dst, prefix, indent, depth+1)
+	if dst, at, ok = indentAny(dst, src, at, prefix, indent, depth+1); !ok {
+		return nil, 0, false
+	}
+
+	for at < len(src) {
+		switch c, at = src[at], at+1; c {
+		case ' ', '\r', '\t', '\n':
+		case ',':
+			dst = append(dst, ',')
+			goto arrAny
+		case ']':
+			dst = indentLine(dst, prefix, indent, depth)
+			dst = append(dst, ']')
+			return dst, at, true
+		default:
+			return nil, 0, false
+		}
+	}
+
+	return nil, 0, false
+
+finNeg:
+	if at == len(src) {
+		return nil, 0, false
+	}
+	if c, at = src[at], at+1; c == '0' {
+		goto fin0
+	}
+	if !isNat(c) {
+		return nil, 0, false
+	}
+
+fin1:
+	for ; at < len(src) && isNum(src[at]); at++ {
+	}
+
+fin0:
+	if at == len(src) {
+		dst = append(dst, src[start:at]...)
+		return dst, at, true
+	}
+	c = src[at]
+	if isE(c) {
+		at++
+		goto finE
+	}
+	if c != '.' {
+		dst = append(dst, src[start:at]...)
+		return dst, at, true
+	}
+	at++
+
+	// finDot
+	if at == len(src) {
+		return nil, 0, false
+	}
+	if c, at = src[at], at+1; !isNum(c) { // first char after dot must be num
+		return nil, 0, false
+	}
+
+	for ; at < len(src) && isNum(src[at]); at++ {
+	}
+
+	if at == len(src) || !isE(src[at]) {
+		dst = append(dst, src[start:at]...)
+		return dst, at, true
+	}
+	at++
+
+finE:
+	if at == len(src) {
+		return nil, 0, false
+	}
+	if c, at = src[at], at+1; c == '+' || c == '-' {
+		if at == len(src) {
+			return nil, 0, false
+		}
+		c, at = src[at], at+1
+	}
+	if !isNum(c) { // first after e (and +/-) must be num
+		return nil, 0, false
+	}
+	for ; at < len(src) && isNum(src[at]); at++ {
+	}
+	dst = append(dst, src[start:at]...)
+	return dst, at, true
+}
+
+// indentLine begins a new line for a value depth levels deep.
+func indentLine(dst []byte, prefix, indent string, depth int) []byte {
+	dst = append(dst, '\n')
+	dst = append(dst, prefix...)
+	for i := 0; i < depth; i++ {
+		dst = append(dst, indent...)
+	}
+	return dst
+}
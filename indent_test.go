@@ -0,0 +1,51 @@
+package chkjson
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestAppendIndent(t *testing.T) {
+	tests := append([]string(nil), edgeCases...)
+	tests = append(tests,
+		" \n{\"a\" : [ 1, {}, [ ] , \"<>\" ], \"b\":{ }} \n\t",
+		`{"a":[{"b":1}],"c":{"d":[[],[[]]]}}`,
+		`[1,]`,
+		`{"a":1,}`,
+		`{"a" 1}`,
+		`{1:1}`,
+		`[1 2]`,
+	)
+	for i := 0; i < 3; i++ {
+		tests = append(tests, string(genBig()))
+	}
+
+	for _, test := range tests {
+		for _, pi := range [][2]string{
+			{"", "\t"},
+			{">", "--"},
+			{"", ""},
+		} {
+			prefix, indent := pi[0], pi[1]
+
+			buf := new(bytes.Buffer)
+			expErr := json.Indent(buf, []byte(test), prefix, indent)
+			exp := buf.Bytes()
+			if expErr != nil {
+				exp = nil
+			}
+
+			got, ok := AppendIndent(nil, []byte(test), prefix, indent)
+			if ok != (expErr == nil) || !bytes.Equal(got, exp) {
+				t.Errorf("«%s» (%q, %q): got «%s» (ok? %v) != exp «%s» (err %v)",
+					test, prefix, indent, got, ok, exp, expErr)
+			}
+			gotStr, okStr := AppendIndentString([]byte("x"), test, prefix, indent)
+			if ok != okStr || ok && !bytes.Equal(gotStr, append([]byte("x"), got...)) {
+				t.Errorf("«%s» (%q, %q): got string «%s» (ok? %v) != «%s» (ok? %v)",
+					test, prefix, indent, gotStr, okStr, got, ok)
+			}
+		}
+	}
+}
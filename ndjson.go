@@ -0,0 +1,121 @@
+package chkjson
+
+import (
+	"unsafe"
+)
+
+// LineError describes an invalid line in newline delimited JSON.
+type LineError struct {
+	// Index is the zero based index of the invalid line.
+	Index int
+	// Offset is the offset of the first byte of the line in the input.
+	Offset int
+
+	// Error describes why the line is invalid. Its offset, line, and column
+	// are positioned within the whole input, not just the line.
+	*Error
+}
+
+// ValidNDJSON validates newline delimited JSON (NDJSON, also known as JSON
+// Lines), where each line is one JSON value. Lines end in "\n" or "\r\n".
+// Lines that are empty or only whitespace, such as a trailing blank line, are
+// skipped.
+//
+// This appends a LineError for every invalid line to bad and returns the
+// updated slice; all lines are valid if nothing is appended. Validating only
+// allocates for invalid lines.
+func ValidNDJSON(b []byte, bad []LineError) []LineError {
+	return ValidNDJSONString(*(*string)(unsafe.Pointer(&b)), bad)
+}
+
+// ValidNDJSONString is exactly like ValidNDJSON, but for strings.
+func ValidNDJSONString(s string, bad []LineError) []LineError {
+	for idx, start := 0, 0; start < len(s); idx++ {
+		end := nextLine(s, start)
+		line := s[start:end]
+		if !ValidString(line) && !isBlank(line) {
+			p := parser{in: line}
+			at, r := p.all()
+			err := p.error(at, r)
+			err.Offset += start
+			err.Line = idx + 1
+			bad = append(bad, LineError{idx, start, err})
+		}
+		start = end + 1
+	}
+	return bad
+}
+
+// CompactNDJSON compacts every line of newline delimited JSON in place,
+// keeping the newlines that end each line. A "\r\n" line ending is compacted
+// to "\n". Lines that are empty or only whitespace are removed.
+//
+// If dropInvalid is true, invalid lines are removed as well. Otherwise, this
+// returns nil and false if any line is invalid, in which case b's contents
+// are unspecified. Use ValidNDJSON first to find out which lines are invalid.
+func CompactNDJSON(b []byte, dropInvalid bool) ([]byte, bool) {
+	s := *(*string)(unsafe.Pointer(&b))
+	w := 0
+	for start := 0; start < len(b); {
+		end := nextLine(s, start)
+		next := end + 1
+		if isBlank(s[start:end]) {
+			start = next
+			continue
+		}
+
+		// compact cannot read past the end of the slice it is given,
+		// meaning we can compact just this line and write it to w.
+		lw, r, ok := compact(b[:end], w, start)
+		if ok {
+			r = skipSpaceBytes(b[:end], r)
+			ok = r == end
+		}
+		start = next
+
+		switch {
+		case ok:
+			w = lw
+			if end < len(b) {
+				b[w] = '\n'
+				w++
+			}
+		case !dropInvalid:
+			return nil, false
+		}
+	}
+	return b[:w], true
+}
+
+// nextLine returns the offset of the '\n' ending the line starting at
+// s[start], or len(s) if the line is not ended.
+func nextLine(s string, start int) int {
+	for end := start; end < len(s); end++ {
+		if s[end] == '\n' {
+			return end
+		}
+	}
+	return len(s)
+}
+
+func isBlank(s string) bool {
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case ' ', '\r', '\t', '\n':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func skipSpaceBytes(b []byte, at int) int {
+	for ; at < len(b); at++ {
+		switch b[at] {
+		case ' ', '\r', '\t', '\n':
+		default:
+			return at
+		}
+	}
+	return at
+}
@@ -0,0 +1,91 @@
+package chkjson
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNDJSON(t *testing.T) {
+	for _, test := range []struct {
+		in      string
+		bad     []LineError // only Index, Offset, and Error.Offset are checked
+		compact string      // if no invalid lines
+		dropped string      // with invalid lines dropped
+	}{
+		{"", nil, "", ""},
+		{"\n\n", nil, "", ""},
+		{"1", nil, "1", "1"},
+		{"1\n", nil, "1\n", "1\n"},
+		{
+			"{\"a\": 1}\n[1, 2]\n\"x\"\n",
+			nil,
+			"{\"a\":1}\n[1,2]\n\"x\"\n",
+			"{\"a\":1}\n[1,2]\n\"x\"\n",
+		},
+		{
+			"{\"a\": 1}\r\n [1, 2] \r\n\r\n\n  \t\r\n",
+			nil,
+			"{\"a\":1}\n[1,2]\n",
+			"{\"a\":1}\n[1,2]\n",
+		},
+		{
+			"{\"a\": 1}\n{\"a\" 2}\r\n\n[1,\n3",
+			[]LineError{
+				{Index: 1, Offset: 9, Error: &Error{Offset: 14}},
+				{Index: 3, Offset: 19, Error: &Error{Offset: 22}},
+			},
+			"",
+			"{\"a\":1}\n3",
+		},
+		{
+			"1 2\n  true  \n",
+			[]LineError{{Index: 0, Offset: 0, Error: &Error{Offset: 2}}},
+			"",
+			"true\n",
+		},
+	} {
+		bad := ValidNDJSON([]byte(test.in), nil)
+		if len(bad) != len(test.bad) {
+			t.Errorf("%q: got %d bad lines != exp %d", test.in, len(bad), len(test.bad))
+		} else {
+			for i, got := range bad {
+				exp := test.bad[i]
+				if got.Index != exp.Index || got.Offset != exp.Offset || got.Error.Offset != exp.Error.Offset || got.Line != exp.Index+1 {
+					t.Errorf("%q: bad line #%d got %d at %d (err %v) != exp %d at %d (err offset %d)",
+						test.in, i, got.Index, got.Offset, got.Error, exp.Index, exp.Offset, exp.Error.Offset)
+				}
+			}
+		}
+		if strBad := ValidNDJSONString(test.in, nil); len(strBad) != len(bad) {
+			t.Errorf("%q: got %d bad lines for string != %d for bytes", test.in, len(strBad), len(bad))
+		}
+
+		got, ok := CompactNDJSON([]byte(test.in), false)
+		if ok != (len(test.bad) == 0) {
+			t.Errorf("%q: compact got ok? %v", test.in, ok)
+		}
+		if ok && string(got) != test.compact {
+			t.Errorf("%q: compact got %q != exp %q", test.in, got, test.compact)
+		}
+
+		got, ok = CompactNDJSON([]byte(test.in), true)
+		if !ok || string(got) != test.dropped {
+			t.Errorf("%q: compact dropping got %q (ok? %v) != exp %q", test.in, got, ok, test.dropped)
+		}
+	}
+}
+
+func TestNDJSONBig(t *testing.T) {
+	var lines []string
+	for i := 0; i < 5; i++ {
+		lines = append(lines, string(genBig()))
+	}
+	in := strings.Join(lines, "\r\n")
+	if bad := ValidNDJSONString(in, nil); len(bad) != 0 {
+		t.Errorf("unexpected bad lines: %v", bad)
+	}
+	got, ok := CompactNDJSON([]byte(in), false)
+	if exp := strings.Join(lines, "\n"); !ok || string(got) != exp {
+		t.Errorf("compact got ok? %v, equal? %v", ok, string(got) == exp)
+	}
+}
@@ -0,0 +1,128 @@
+package chkjson
+
+import (
+	"math"
+	"strconv"
+)
+
+// NumberForm configures how Options rewrites numbers when compacting.
+type NumberForm uint8
+
+const (
+	// NumbersAsIs keeps numbers exactly as they are in the input.
+	NumbersAsIs NumberForm = iota
+
+	// NumbersLossless rewrites numbers without changing their value:
+	// trailing zeros in fractions are removed (as is the dot if nothing
+	// remains after it), the exponent is written with a lowercase e and
+	// without a + sign or leading zeros, a zero exponent is removed, and
+	// any zero, including -0, is written as 0. For example, 1.50E+02
+	// becomes 1.5e2 and 100e0 becomes 100.
+	NumbersLossless
+
+	// NumbersShortest rewrites numbers to the shortest form that parses
+	// back to the same float64, which can change the value of numbers
+	// that float64 cannot represent exactly. For example, 1.0E+02 becomes
+	// 100 and 1000000 becomes 1e6. If the float64 form is not finite or
+	// would be longer than the NumbersLossless form, the NumbersLossless
+	// form is used instead.
+	NumbersShortest
+)
+
+// appendNum appends the valid number num to dst in the given form.
+//
+// No form is ever longer than num, and num is only read before dst grows past
+// where num was read from, meaning num can be rewritten in place.
+func appendNum(dst []byte, num string, form NumberForm, scratch *[]byte) []byte {
+	switch form {
+	case NumbersLossless:
+		return appendLosslessNum(dst, num)
+	case NumbersShortest:
+		f, _ := strconv.ParseFloat(num, 64)
+		start := len(dst)
+		dst = appendLosslessNum(dst, num)
+		if math.IsInf(f, 0) {
+			return dst
+		}
+		*scratch = appendFloat((*scratch)[:0], f, floatShortest)
+		if len(*scratch) <= len(dst)-start {
+			dst = append(dst[:start], *scratch...)
+		}
+		return dst
+	default:
+		return append(dst, num...)
+	}
+}
+
+// appendLosslessNum appends the valid number num in its NumbersLossless form.
+func appendLosslessNum(dst []byte, num string) []byte {
+	// We first find the pieces of the number, and then append the pieces
+	// we keep in order, meaning we only append from num at or after where
+	// we have appended to.
+	neg := num[0] == '-'
+	intStart := 0
+	if neg {
+		intStart = 1
+	}
+	intEnd := intStart
+	for intEnd < len(num) && isNum(num[intEnd]) {
+		intEnd++
+	}
+
+	fracStart, fracEnd := intEnd, intEnd
+	if fracStart < len(num) && num[fracStart] == '.' {
+		fracStart++
+		for fracEnd = fracStart; fracEnd < len(num) && isNum(num[fracEnd]); fracEnd++ {
+		}
+	}
+	expStart := fracEnd
+	trimmed := fracEnd
+	for trimmed > fracStart && num[trimmed-1] == '0' {
+		trimmed--
+	}
+
+	if num[intStart:intEnd] == "0" && trimmed == fracStart {
+		return append(dst, '0')
+	}
+
+	if neg {
+		dst = append(dst, '-')
+	}
+	dst = append(dst, num[intStart:intEnd]...)
+	if trimmed > fracStart {
+		dst = append(dst, num[fracStart-1:trimmed]...)
+	}
+
+	if expStart == len(num) {
+		return dst
+	}
+	at := expStart + 1 // skip e or E
+	expNeg := num[at] == '-'
+	if expNeg || num[at] == '+' {
+		at++
+	}
+	for at < len(num)-1 && num[at] == '0' {
+		at++
+	}
+	if num[at:] == "0" {
+		return dst
+	}
+	dst = append(dst, 'e')
+	if expNeg {
+		dst = append(dst, '-')
+	}
+	return append(dst, num[at:]...)
+}
+
+// intLen returns the number of bytes needed to write x in base 10.
+func intLen(x int) int {
+	n := 1
+	if x < 0 {
+		n++
+		x = -x
+	}
+	for ; x >= 10; x /= 10 {
+		n++
+	}
+	return n
+}
@@ -0,0 +1,160 @@
+package chkjson
+
+import (
+	"unsafe"
+)
+
+// Options configures stricter validating and compacting than the bool
+// returning functions in this package provide.
+//
+// The zero value validates and compacts exactly like Valid, AppendCompact and
+// Compact. Options functions are slower than their bool returning
+// counterparts, but return an *Error describing why input was rejected.
+type Options struct {
+	// MaxDepth, if positive, is the maximum nesting depth of objects and
+	// arrays. A scalar has depth 0 and [] has depth 1, meaning a MaxDepth
+	// of 1 allows [1] but not [[1]].
+	//
+	// Valid, AppendCompact and Compact recurse once per nesting level.
+	// Untrusted input that is deeply nested can grow the goroutine stack
+	// without bound; setting MaxDepth fails such input early with an
+	// *Error of kind KindDepth.
+	MaxDepth int
+
+	// StrictUTF8, if true, rejects strings that contain invalid UTF-8 with
+	// an *Error of kind KindUTF8. RFC 8259 requires UTF-8 for JSON that is
+	// exchanged between systems, but by default, and as with
+	// encoding/json, any bytes that are not control characters are allowed
+	// in strings. Overlong encodings and encoded surrogate halves are
+	// invalid UTF-8.
+	//
+	// Escaped characters are not affected by this option.
+	StrictUTF8 bool
+
+	// RejectLoneSurrogates, if true, rejects strings containing \u escapes
+	// of UTF-16 surrogate halves that are not paired with an *Error of kind
+	// KindSurrogate. Every high surrogate escape (\ud800 through \udbff)
+	// must be immediately followed by a low surrogate escape (\udc00
+	// through \udfff), and low surrogate escapes cannot appear otherwise.
+	//
+	// Unpaired surrogates cannot be represented in UTF-8, and consumers
+	// such as those requiring I-JSON (RFC 7493) reject them.
+	RejectLoneSurrogates bool
+
+	// RejectDuplicateKeys, if true, rejects objects containing the same
+	// key twice with an *Error of kind KindDuplicateKey, positioned at the
+	// second key. Keys are compared after unescaping, meaning "a" and
+	// "\u0061" are duplicates.
+	//
+	// RFC 8259 leaves the meaning of duplicate keys undefined, and
+	// consumers differ in which value they keep.
+	//
+	// Objects with up to eight keys are checked without allocating.
+	RejectDuplicateKeys bool
+
+	// RejectUnsafeIntegers, if true, rejects integers outside of
+	// -(2^53-1) through 2^53-1 with an *Error of kind KindUnsafeInteger,
+	// positioned at the start of the number. Integers outside of this range
+	// cannot be represented exactly by IEEE 754 doubles, which is how
+	// JavaScript and many other consumers parse numbers.
+	//
+	// Only numbers written as integers, that is, without a fraction or
+	// exponent, are checked.
+	RejectUnsafeIntegers bool
+
+	// RejectHugeNumbers, if true, rejects numbers whose magnitude is too
+	// large for an IEEE 754 double, such as 1e400, with an *Error of kind
+	// KindNumberRange, positioned at the start of the number. Such numbers
+	// parse as infinity, or fail to parse, in most consumers. Numbers too
+	// small in magnitude are not rejected, as they parse as zero.
+	RejectHugeNumbers bool
+
+	// MinimalEscapes, if true, rewrites every string to its shortest
+	// escaping when compacting. Escapes are decoded unless EscapeString
+	// would escape what they encode, in which case the shortest escape is
+	// used (\n rather than \u000a, \b rather than \u0008). Thus, "A\/b"
+	// and "\u0041/b" both compact to "A/b".
+	//
+	// Escapes of unpaired surrogate halves cannot be decoded and are kept,
+	// with their hex digits lowercased. Raw bytes are always kept as is.
+	// This never makes the output longer, meaning compacting in place
+	// still works.
+	//
+	// This option has no effect when only validating.
+	MinimalEscapes bool
+
+	// Numbers configures how numbers are rewritten when compacting. By
+	// default, numbers are kept as is. No form makes a number longer,
+	// meaning compacting in place still works.
+	//
+	// This option has no effect when only validating.
+	Numbers NumberForm
+}
+
+func (o *Options) parser(in string) parser {
+	return parser{
+		in:         in,
+		maxDepth:   o.MaxDepth,
+		strictUTF8: o.StrictUTF8,
+		surrogates: o.RejectLoneSurrogates,
+		dupKeys:    o.RejectDuplicateKeys,
+		safeInts:   o.RejectUnsafeIntegers,
+		finiteNums: o.RejectHugeNumbers,
+		minimal:    o.MinimalEscapes,
+		numbers:    o.Numbers,
+	}
+}
+
+// Valid returns nil if b is valid JSON that satisfies the options, and
+// otherwise an *Error describing why it is not.
+func (o *Options) Valid(b []byte) error {
+	return o.ValidString(*(*string)(unsafe.Pointer(&b)))
+}
+
+// ValidString is exactly like Valid, but for strings.
+func (o *Options) ValidString(s string) error {
+	p := o.parser(s)
+	if at, r := p.all(); r != rOK {
+		return p.error(at, r)
+	}
+	return nil
+}
+
+// AppendCompact is like the package level AppendCompact, but respects the
+// options and returns an *Error if src is invalid or does not satisfy the
+// options. On error, this returns a nil slice.
+//
+// As with the package level AppendCompact, it is valid to pass (src[:0], src)
+// to this function.
+func (o *Options) AppendCompact(dst, src []byte) ([]byte, error) {
+	return o.AppendCompactString(dst, *(*string)(unsafe.Pointer(&src)))
+}
+
+// AppendCompactString is exactly like AppendCompact, but for compacting
+// strings.
+func (o *Options) AppendCompactString(dst []byte, src string) ([]byte, error) {
+	p := o.parser(src)
+	p.dst, p.pack = dst, true
+	if at, r := p.all(); r != rOK {
+		return nil, p.error(at, r)
+	}
+	return p.dst, nil
+}
+
+// Compact is like the package level Compact, but respects the options and
+// returns an *Error if b is invalid or does not satisfy the options. On
+// error, this returns a nil slice and b's contents are unspecified; the
+// returned *Error's snippet only includes input that was not yet overwritten.
+func (o *Options) Compact(b []byte) ([]byte, error) {
+	return o.AppendCompact(b[:0], b)
+}
+
+// overlaps returns whether dst's backing array overlaps the memory of s.
+func overlaps(dst []byte, s string) bool {
+	if cap(dst) == 0 || len(s) == 0 {
+		return false
+	}
+	d0 := uintptr(unsafe.Pointer(&dst[:cap(dst)][0]))
+	s0 := *(*uintptr)(unsafe.Pointer(&s)) // a string's first word is its data pointer
+	return d0 < s0+uintptr(len(s)) && s0 < d0+uintptr(cap(dst))
+}
@@ -0,0 +1,358 @@
+package chkjson
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestOptionsDepth(t *testing.T) {
+	for _, test := range []struct {
+		in     string
+		depth  int
+		offset int // -1 if valid
+	}{
+		{`1`, 1, -1},
+		{`[1]`, 1, -1},
+		{`{"a": 1}`, 1, -1},
+		{`[[1]]`, 1, 1},
+		{`[{}, [], {"a": [2]}]`, 2, 15},
+		{`[{}, [], {"a": [2]}]`, 3, -1},
+		{`[[1]]`, 0, -1}, // no limit
+		{strings.Repeat("[", 1000) + strings.Repeat("]", 1000), 1000, -1},
+		{strings.Repeat("[", 1001) + strings.Repeat("]", 1001), 1000, 1000},
+
+		// Hostile input must fail once past the limit, not once parsed.
+		{strings.Repeat("[", 10000000), 100, 100},
+
+		// Syntax errors before the limit is hit are still syntax errors.
+		{`[[1}]`, 3, -2},
+	} {
+		o := &Options{MaxDepth: test.depth}
+
+		chk := func(name string, err error) {
+			switch test.offset {
+			case -1:
+				if err != nil {
+					t.Errorf("%s «%.20s» (depth %d): unexpected err %v", name, test.in, test.depth, err)
+				}
+			case -2:
+				if err == nil || err.(*Error).Kind != KindSyntax {
+					t.Errorf("%s «%.20s» (depth %d): got err %v, exp syntax error", name, test.in, test.depth, err)
+				}
+			default:
+				if err == nil {
+					t.Errorf("%s «%.20s» (depth %d): unexpectedly valid", name, test.in, test.depth)
+					return
+				}
+				got := err.(*Error)
+				if got.Kind != KindDepth || got.Offset != test.offset {
+					t.Errorf("%s «%.20s» (depth %d): got kind %d at %d, exp depth at %d", name, test.in, test.depth, got.Kind, got.Offset, test.offset)
+				}
+			}
+		}
+
+		chk("Valid", o.ValidString(test.in))
+
+		got, err := o.AppendCompactString(nil, test.in)
+		chk("AppendCompact", err)
+		if err != nil {
+			_, err = o.Compact([]byte(test.in))
+			chk("Compact", err)
+			continue
+		}
+		exp, _ := AppendCompactString(nil, test.in)
+		if string(got) != string(exp) {
+			t.Errorf("AppendCompact «%.20s» (depth %d): got %s != exp %s", test.in, test.depth, got, exp)
+		}
+
+		got, err = o.Compact([]byte(test.in))
+		chk("Compact", err)
+		if string(got) != string(exp) {
+			t.Errorf("Compact «%.20s» (depth %d): got %s != exp %s", test.in, test.depth, got, exp)
+		}
+	}
+}
+
+func TestOptionsCompactInplaceErr(t *testing.T) {
+	in := []byte("[\n  1,\n  2,\n  3 4]")
+	_, err := new(Options).Compact(in)
+	if err == nil {
+		t.Fatal("unexpectedly valid")
+	}
+	got := err.(*Error)
+	if got.Offset != 16 || got.Line != 4 || got.Column != 5 {
+		t.Errorf("got offset %d, line %d, column %d != exp 16, 4, 5", got.Offset, got.Line, got.Column)
+	}
+	if got.Snippet != "\n  2,\n  3 4]" {
+		t.Errorf("got snippet %q != exp %q", got.Snippet, "\n  2,\n  3 4]")
+	}
+}
+
+func TestOptionsStrictUTF8(t *testing.T) {
+	for _, test := range []struct {
+		in     string
+		offset int // -1 if valid
+	}{
+		{`"foo"`, -1},
+		{"\"\xe2\x80\xa8\xf0\x9f\x98\x80é\"", -1},
+		{`"\ud800"`, -1}, // escapes are not checked
+		{"\"a\xff\"", 2},
+		{"\"ab\xe2\x80\"", 3},       // truncated
+		{"\"\xc0\xaf\"", 1},         // overlong '/'
+		{"\"\xe0\x80\xaf\"", 1},     // overlong '/'
+		{"\"\xed\xa0\x80\"", 1},     // encoded surrogate
+		{"\"\xf4\x90\x80\x80\"", 1}, // above U+10FFFF
+		{"{\"k\xff\": 1}", 3},       // keys are checked too
+		{"[\"ok\", {\"a\": \"\x80\"}]", 14},
+	} {
+		o := &Options{StrictUTF8: true}
+
+		chk := func(name string, err error) {
+			if test.offset == -1 {
+				if err != nil {
+					t.Errorf("%s %q: unexpected err %v", name, test.in, err)
+				}
+				return
+			}
+			if err == nil {
+				t.Errorf("%s %q: unexpectedly valid", name, test.in)
+				return
+			}
+			got := err.(*Error)
+			if got.Kind != KindUTF8 || got.Offset != test.offset {
+				t.Errorf("%s %q: got kind %d at %d, exp UTF-8 at %d", name, test.in, got.Kind, got.Offset, test.offset)
+			}
+		}
+
+		chk("Valid", o.ValidString(test.in))
+		got, err := o.AppendCompactString(nil, test.in)
+		chk("AppendCompact", err)
+		if err == nil && string(got) != test.in {
+			t.Errorf("AppendCompact %q: got %q", test.in, got)
+		}
+		_, err = o.Compact([]byte(test.in))
+		chk("Compact", err)
+
+		// Without the option, all of the above is valid.
+		if !ValidString(test.in) {
+			t.Errorf("%q: unexpectedly invalid without StrictUTF8", test.in)
+		}
+	}
+}
+
+func TestOptionsLoneSurrogates(t *testing.T) {
+	for _, test := range []struct {
+		in     string
+		offset int // -1 if valid
+		reason string
+	}{
+		{`"\ud83d\ude00"`, -1, ""},
+		{`"\ud83d\ude00 \u0041\uffff"`, -1, ""},
+		{`"a\ud800"`, 8, `expected \u escape of low surrogate after high surrogate`},
+		{`"\ud800a"`, 7, `expected \u escape of low surrogate after high surrogate`},
+		{`"\ud800\n"`, 7, `expected \u escape of low surrogate after high surrogate`},
+		{`"\ud800A"`, 7, `expected \u escape of low surrogate after high surrogate`},
+		{`"\ud800\ud800"`, 7, `expected \u escape of low surrogate after high surrogate`},
+		{`"\ud800\uzzzz"`, 9, `expected four hex digits after '\u'`},
+		{`"ab\udc00"`, 3, `expected high surrogate escape before low surrogate`},
+		{`"\ud83d\ude00\ude00"`, 13, `expected high surrogate escape before low surrogate`},
+		{`{"\udfff": 1}`, 2, `expected high surrogate escape before low surrogate`},
+	} {
+		o := &Options{RejectLoneSurrogates: true}
+
+		chk := func(name string, err error) {
+			if test.offset == -1 {
+				if err != nil {
+					t.Errorf("%s %s: unexpected err %v", name, test.in, err)
+				}
+				return
+			}
+			if err == nil {
+				t.Errorf("%s %s: unexpectedly valid", name, test.in)
+				return
+			}
+			got := err.(*Error)
+			if got.Offset != test.offset || got.Reason != test.reason {
+				t.Errorf("%s %s: got %q at %d, exp %q at %d", name, test.in, got.Reason, got.Offset, test.reason, test.offset)
+			}
+			if expSurrogate := test.reason[len(test.reason)-9:] == "surrogate"; expSurrogate != (got.Kind == KindSurrogate) {
+				t.Errorf("%s %s: got kind %d", name, test.in, got.Kind)
+			}
+		}
+
+		chk("Valid", o.ValidString(test.in))
+		got, err := o.AppendCompactString(nil, test.in)
+		chk("AppendCompact", err)
+		if err == nil && string(got) != test.in {
+			t.Errorf("AppendCompact %s: got %s", test.in, got)
+		}
+		_, err = o.Compact([]byte(test.in))
+		chk("Compact", err)
+	}
+}
+
+func TestOptionsDuplicateKeys(t *testing.T) {
+	many := func(n int, last string) string {
+		var sb strings.Builder
+		sb.WriteString("{")
+		for i := 0; i < n; i++ {
+			sb.WriteString(`"k` + strconv.Itoa(i) + `": 1, `)
+		}
+		sb.WriteString(`"` + last + `": 2}`)
+		return sb.String()
+	}
+
+	for _, test := range []struct {
+		in     string
+		offset int // -1 if valid
+	}{
+		{`{}`, -1},
+		{`{"a": 1, "b": {"a": 2}, "c": [{"a": 3}, {"a": 4}]}`, -1},
+		{`{"a": 1, "a": 2}`, 9},
+		{`{"\u0061": 1, "a": 2}`, 14},
+		{`{"\/": 1, "/": 2}`, 10},
+		{`{"\ud83d\ude00": 1, "\uD83D\uDE00": 2}`, 20},
+		{"{\"\xff\": 1, \"\xff\": 2}", 9},
+		{`{"ab": 1, "a": 2, "abc": 3, "ab": 4}`, 28},
+		{`[{"a": {"b": 1, "b": 2}}]`, 16},
+		{many(8, "k9"), -1},
+		{many(20, "k21"), -1},
+		{many(8, "k7"), 73},
+		{many(20, "k19"), 191},
+		{many(20, `k\u0031\u0039`), 191},
+	} {
+		o := &Options{RejectDuplicateKeys: true}
+
+		chk := func(name string, err error) {
+			if test.offset == -1 {
+				if err != nil {
+					t.Errorf("%s %s: unexpected err %v", name, test.in, err)
+				}
+				return
+			}
+			if err == nil {
+				t.Errorf("%s %s: unexpectedly valid", name, test.in)
+				return
+			}
+			got := err.(*Error)
+			if got.Kind != KindDuplicateKey || got.Offset != test.offset {
+				t.Errorf("%s %s: got kind %d at %d, exp duplicate key at %d", name, test.in, got.Kind, got.Offset, test.offset)
+			}
+		}
+
+		chk("Valid", o.ValidString(test.in))
+		_, err := o.AppendCompactString(nil, test.in)
+		chk("AppendCompact", err)
+		_, err = o.Compact([]byte(test.in))
+		chk("Compact", err)
+	}
+}
+
+func TestOptionsDuplicateKeysNoAlloc(t *testing.T) {
+	in := []byte(`{"a": 1, "b": {"c": 2, "de": 3}, "e": [{"f": 4}, {"f": 5}], "g": 6}`)
+	o := &Options{RejectDuplicateKeys: true}
+	if allocs := testing.AllocsPerRun(100, func() { o.Valid(in) }); allocs != 0 {
+		t.Errorf("got %v allocs != exp 0", allocs)
+	}
+}
+
+func TestOptionsMinimalEscapes(t *testing.T) {
+	for _, test := range []struct {
+		in  string
+		exp string
+	}{
+		{`"A\/b"`, `"A/b"`},
+		{` [ "\u0041/b" ] `, `["A/b"]`},
+		{`"\u00e9"`, "\"\xc3\xa9\""},
+		{`"\ud83d\ude00"`, "\"\xf0\x9f\x98\x80\""},
+		{`"\u000A\u0008\u000c\u001F\u0022\u005c\\\"\b\f"`, `"\n\b\f\u001f\"\\\\\"\b\f"`},
+		{`"\uD800x"`, `"\ud800x"`},
+		{`"\uDC00\uD83D\uDE00"`, `"\udc00` + "\xf0\x9f\x98\x80\""},
+		{`"\u2028<&>\u007f"`, "\"\xe2\x80\xa8<&>\x7f\""},
+		{`"\ufffd"`, "\"\xef\xbf\xbd\""},
+		{"\"\xff\u0041\"", "\"\xffA\""},
+		{`{"\u0061": "x\/y", "b": ["\u0063"]}`, `{"a":"x/y","b":["c"]}`},
+		{`"plain"`, `"plain"`},
+	} {
+		o := &Options{MinimalEscapes: true}
+		got, err := o.AppendCompactString(nil, test.in)
+		if err != nil || string(got) != test.exp {
+			t.Errorf("%s: got %s (err %v) != exp %s", test.in, got, err, test.exp)
+		}
+		inplace, err := o.Compact([]byte(test.in))
+		if err != nil || string(inplace) != test.exp {
+			t.Errorf("%s: got inplace %s (err %v) != exp %s", test.in, inplace, err, test.exp)
+		}
+
+		var expv, gotv interface{}
+		json.Unmarshal([]byte(test.in), &expv)
+		json.Unmarshal(got, &gotv)
+		if !reflect.DeepEqual(expv, gotv) {
+			t.Errorf("%s: unmarshaled %v != %v", test.in, gotv, expv)
+		}
+	}
+
+	o := &Options{MinimalEscapes: true, RejectDuplicateKeys: true}
+	if _, err := o.Compact([]byte(`{"a": 1, "\u0061": 2}`)); err == nil {
+		t.Error("unexpectedly no duplicate key error")
+	}
+}
+
+func TestOptionsNumbers(t *testing.T) {
+	for _, test := range []struct {
+		in       string
+		lossless string
+		shortest string
+	}{
+		{"1.0E+02", "1e2", "100"},
+		{"-0", "0", "0"},
+		{"100e0", "100", "100"},
+		{"1.500000", "1.5", "1.5"},
+		{"0.000", "0", "0"},
+		{"-0.0e-05", "0", "0"},
+		{"1E-007", "1e-7", "1e-7"},
+		{"1e+00", "1", "1"},
+		{"1000000", "1000000", "1e6"},
+		{"123456789012345678901234567890", "123456789012345678901234567890", "12345678901234568e13"},
+		{"0.1", "0.1", "0.1"},
+		{"0.001", "0.001", "1e-3"},
+		{"0.0012", "0.0012", "12e-4"},
+		{"1e400", "1e400", "1e400"},
+		{"-1e400", "-1e400", "-1e400"},
+		{"1e-400", "1e-400", "0"},
+		{"-12.50e+1", "-12.5e1", "-125"},
+		{"0.30000000000000004441", "0.30000000000000004441", "0.30000000000000004"},
+		{"5e-324", "5e-324", "5e-324"},
+		{"10.0e-0", "10", "10"},
+		{` [ 1.0 , { "a" : 2E2 } ] `, `[1,{"a":2e2}]`, `[1,{"a":200}]`},
+	} {
+		for _, form := range []struct {
+			form NumberForm
+			exp  string
+		}{
+			{NumbersAsIs, test.in},
+			{NumbersLossless, test.lossless},
+			{NumbersShortest, test.shortest},
+		} {
+			o := &Options{Numbers: form.form}
+			exp, _ := AppendCompactString(nil, form.exp)
+			got, err := o.AppendCompactString(nil, test.in)
+			if err != nil || string(got) != string(exp) {
+				t.Errorf("%s (form %d): got %s (err %v) != exp %s", test.in, form.form, got, err, exp)
+			}
+			inplace, err := o.Compact([]byte(test.in))
+			if err != nil || string(inplace) != string(exp) {
+				t.Errorf("%s (form %d): got inplace %s (err %v) != exp %s", test.in, form.form, inplace, err, exp)
+			}
+		}
+
+		if f, err := strconv.ParseFloat(test.in, 64); err == nil {
+			if got, _ := strconv.ParseFloat(test.shortest, 64); got != f {
+				t.Errorf("%s: shortest %s parses to %v != %v", test.in, test.shortest, got, f)
+			}
+		}
+	}
+}
@@ -0,0 +1,499 @@
+package chkjson
+
+import (
+	"math"
+	"strconv"
+	"unicode/utf8"
+	"unsafe"
+)
+
+// parser is the configurable sibling of any and packAny. It backs the error
+// returning functions and Options.
+//
+// Unlike any, parser tracks why parsing fails, and the returned offset on
+// failure is always the offset of the byte that caused the failure. If pack
+// is true, the parser appends the compact form of what it parses to dst,
+// exactly as packAny does.
+//
+// Being configurable, the parser is split into a function per JSON type rather
+// than one giant state machine. It is slower than any and packAny and is only
+// used when the caller asks for more than a bool.
+type parser struct {
+	in   string
+	dst  []byte
+	pack bool
+
+	maxDepth   int
+	depth      int
+	strictUTF8 bool
+	surrogates bool
+	dupKeys    bool
+	safeInts   bool
+	finiteNums bool
+	minimal    bool
+	numbers    NumberForm
+
+	scratch []byte // reusable scratch space for unescaping keys and numbers
+
+	// We track lines as we skip whitespace, which is the only place
+	// newlines can be, so that errors can be positioned even after
+	// compacting in place overwrote the input.
+	lines     int
+	lineStart int
+}
+
+// all parses all of p.in, which must be one value optionally surrounded by
+// whitespace.
+func (p *parser) all() (int, reason) {
+	at, r := p.value(0)
+	if r != rOK {
+		return at, r
+	}
+	if at = p.space(at); at != len(p.in) {
+		return at, rEnd
+	}
+	return at, rOK
+}
+
+func (p *parser) value(at int) (int, reason) {
+	in := p.in
+	if at = p.space(at); at == len(in) {
+		return at, rValue
+	}
+	switch in[at] {
+	case '{':
+		return p.obj(at)
+	case '[':
+		return p.arr(at)
+	case '"':
+		return p.str(at)
+	case 't':
+		return p.lit(at, "true", rTrue)
+	case 'f':
+		return p.lit(at, "false", rFalse)
+	case 'n':
+		return p.lit(at, "null", rNull)
+	case '-', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
+		return p.num(at)
+	default:
+		return at, rValue
+	}
+}
+
+// enter is called on every '{' and '[' at in[at] to enforce the depth limit.
+func (p *parser) enter(at int) reason {
+	if p.maxDepth > 0 && p.depth == p.maxDepth {
+		return rDepth
+	}
+	p.depth++
+	if p.pack {
+		p.dst = append(p.dst, p.in[at])
+	}
+	return rOK
+}
+
+// leave is called on every '}' and ']' at in[at].
+func (p *parser) leave(at int) (int, reason) {
+	p.depth--
+	if p.pack {
+		p.dst = append(p.dst, p.in[at])
+	}
+	return at + 1, rOK
+}
+
+func (p *parser) obj(at int) (int, reason) {
+	in := p.in
+	r := p.enter(at)
+	if r != rOK {
+		return at, r
+	}
+
+	if at = p.space(at + 1); at == len(in) || in[at] != '"' {
+		if at < len(in) && in[at] == '}' {
+			return p.leave(at)
+		}
+		return at, rKeyOrObjEnd
+	}
+
+	var keys keySet
+	for {
+		key, dkey := at, len(p.dst)
+		if at, r = p.str(at); r != rOK {
+			return at, r
+		}
+		if p.dupKeys {
+			// If packing, the input may be overwritten as we go, but
+			// the key we just wrote to dst will not be.
+			k := in[key+1 : at-1]
+			if p.pack {
+				k = unsafeString(p.dst[dkey+1 : len(p.dst)-1])
+			}
+			if !keys.add(k, &p.scratch) {
+				return key, rDupKey
+			}
+		}
+
+		if at = p.space(at); at == len(in) || in[at] != ':' {
+			return at, rColon
+		}
+		if p.pack {
+			p.dst = append(p.dst, ':')
+		}
+
+		if at, r = p.value(at + 1); r != rOK {
+			return at, r
+		}
+
+		if at = p.space(at); at == len(in) {
+			return at, rObjNext
+		}
+		switch in[at] {
+		case ',':
+			if p.pack {
+				p.dst = append(p.dst, ',')
+			}
+			if at = p.space(at + 1); at == len(in) || in[at] != '"' {
+				return at, rKey
+			}
+		case '}':
+			return p.leave(at)
+		default:
+			return at, rObjNext
+		}
+	}
+}
+
+func (p *parser) arr(at int) (int, reason) {
+	in := p.in
+	r := p.enter(at)
+	if r != rOK {
+		return at, r
+	}
+
+	if at = p.space(at + 1); at == len(in) {
+		return at, rValueOrArrEnd
+	}
+	if in[at] == ']' {
+		return p.leave(at)
+	}
+	first := at
+	if at, r = p.value(at); r != rOK {
+		if r == rValue && at == first {
+			r = rValueOrArrEnd
+		}
+		return at, r
+	}
+
+	for {
+		if at = p.space(at); at == len(in) {
+			return at, rArrNext
+		}
+		switch in[at] {
+		case ',':
+			if p.pack {
+				p.dst = append(p.dst, ',')
+			}
+			if at, r = p.value(at + 1); r != rOK {
+				return at, r
+			}
+		case ']':
+			return p.leave(at)
+		default:
+			return at, rArrNext
+		}
+	}
+}
+
+// str parses a string beginning with the quote at in[at].
+func (p *parser) str(at int) (int, reason) {
+	in := p.in
+	start := at
+	var r reason
+	for at++; at < len(in); at++ {
+		// Most strings are mostly ASCII that needs no escaping, which we
+		// skip in runs before looking at anything more closely.
+		for at < len(in) && in[at] < utf8.RuneSelf && safeSet[in[at]] {
+			at++
+		}
+		if at == len(in) {
+			break
+		}
+		switch c := in[at]; c {
+		default:
+			// Only non-ASCII gets here; we only decode if we must.
+			if p.strictUTF8 {
+				u, size := utf8.DecodeRuneInString(in[at:])
+				if u == utf8.RuneError && size == 1 {
+					return at, rUTF8
+				}
+				at += size - 1
+			}
+		case 0, 1, 2, 3, 4, 5, 6, 7, 8, 9,
+			10, 11, 12, 13, 14, 15, 16, 17, 18, 19,
+			20, 21, 22, 23, 24, 25, 26, 27, 28, 29,
+			30, 31:
+			return at, rStrChar
+		case '"':
+			at++
+			if p.pack {
+				if p.minimal {
+					p.dst = append(p.dst, '"')
+					p.dst = appendMinimal(p.dst, in[start+1:at-1])
+					p.dst = append(p.dst, '"')
+				} else {
+					p.dst = append(p.dst, in[start:at]...)
+				}
+			}
+			return at, rOK
+		case '\\':
+			esc := at
+			if at, r = errEscape(in, at+1); r != rOK {
+				return at, r
+			}
+			if p.surrogates && in[esc+1] == 'u' {
+				if at, r = pairSurrogate(in, esc, at); r != rOK {
+					return at, r
+				}
+			}
+			at-- // undo the loop increment
+		}
+	}
+	return at, rStrEnd
+}
+
+func (p *parser) lit(at int, lit string, r reason) (int, reason) {
+	at, r = errLit(p.in, at, lit, r)
+	if r == rOK && p.pack {
+		p.dst = append(p.dst, lit...)
+	}
+	return at, r
+}
+
+func (p *parser) num(at int) (int, reason) {
+	start := at
+	at, r := errNum(p.in, at)
+	if r == rOK && p.safeInts && !isSafeInt(p.in[start:at]) {
+		return start, rUnsafeInt
+	}
+	if r == rOK && p.finiteNums && isHugeNum(p.in[start:at]) {
+		return start, rNumRange
+	}
+	if r == rOK && p.pack {
+		p.dst = appendNum(p.dst, p.in[start:at], p.numbers, &p.scratch)
+	}
+	return at, r
+}
+
+func (p *parser) space(at int) int {
+	in := p.in
+	for ; at < len(in); at++ {
+		switch in[at] {
+		case ' ', '\r', '\t':
+		case '\n':
+			p.lines++
+			p.lineStart = at + 1
+		default:
+			return at
+		}
+	}
+	return at
+}
+
+// error returns an *Error for parsing failing at in[at] for reason r.
+func (p *parser) error(at int, r reason) *Error {
+	// If we were packing to a dst that overlaps the input, the input
+	// before what we have written may have been overwritten.
+	from := 0
+	if p.pack && overlaps(p.dst, p.in) {
+		from = len(p.dst)
+	}
+	return newError(p.in, at, r, p.lines+1, at-p.lineStart+1, from)
+}
+
+// errEscape validates the escape sequence following a backslash at in[at-1],
+// returning the offset just past the escape.
+func errEscape(in string, at int) (int, reason) {
+	if at == len(in) {
+		return at, rEscape
+	}
+	switch in[at] {
+	case 'b', 'f', 'n', 'r', 't', '\\', '/', '"':
+		return at + 1, rOK
+	case 'u':
+		for i := at + 1; i < at+5; i++ {
+			if i == len(in) || !isHex(in[i]) {
+				return i, rHex
+			}
+		}
+		return at + 5, rOK
+	default:
+		return at, rEscape
+	}
+}
+
+// pairSurrogate checks that the valid \u escape from in[esc] to in[end] is
+// not an unpaired surrogate half, returning the end of the escape, or if the
+// escape is a high surrogate, the end of the following low surrogate escape.
+func pairSurrogate(in string, esc, end int) (int, reason) {
+	switch u := hex4(in[esc+2:]); {
+	case u >= 0xdc00 && u < 0xe000:
+		return esc, rLoneLow
+	case u >= 0xd800 && u < 0xdc00:
+		if end+1 >= len(in) || in[end] != '\\' || in[end+1] != 'u' {
+			return end, rLoneHigh
+		}
+		low, r := errEscape(in, end+1)
+		if r != rOK {
+			return low, r
+		}
+		if u = hex4(in[end+2:]); u < 0xdc00 || u >= 0xe000 {
+			return end, rLoneHigh
+		}
+		return low, rOK
+	default:
+		return end, rOK
+	}
+}
+
+// hex4 returns the value of the four hex digits at the start of s, which must
+// have already been validated.
+func hex4(s string) rune {
+	var u rune
+	for i := 0; i < 4; i++ {
+		c := s[i]
+		switch {
+		case c <= '9':
+			c -= '0'
+		case c <= 'F':
+			c -= 'A' - 10
+		default:
+			c -= 'a' - 10
+		}
+		u = u<<4 | rune(c)
+	}
+	return u
+}
+
+// errLit validates that the literal lit begins at in[at].
+func errLit(in string, at int, lit string, r reason) (int, reason) {
+	for i := 0; i < len(lit); i++ {
+		if at+i == len(in) || in[at+i] != lit[i] {
+			return at + i, r
+		}
+	}
+	return at + len(lit), rOK
+}
+
+// errNum validates the number beginning at in[at], which must be '-' or a
+// digit.
+func errNum(in string, at int) (int, reason) {
+	if in[at] == '-' {
+		if at++; at == len(in) || !isNum(in[at]) {
+			return at, rNegDigit
+		}
+	}
+	if in[at] == '0' {
+		at++
+	} else {
+		for at++; at < len(in) && isNum(in[at]); at++ {
+		}
+	}
+
+	if at < len(in) && in[at] == '.' {
+		if at++; at == len(in) || !isNum(in[at]) { // first char after dot must be num
+			return at, rDotDigit
+		}
+		for at++; at < len(in) && isNum(in[at]); at++ {
+		}
+	}
+
+	if at < len(in) && isE(in[at]) {
+		if at++; at < len(in) && (in[at] == '+' || in[at] == '-') {
+			at++
+		}
+		if at == len(in) || !isNum(in[at]) { // first after e (and +/-) must be num
+			return at, rExpDigit
+		}
+		for at++; at < len(in) && isNum(in[at]); at++ {
+		}
+	}
+	return at, rOK
+}
+
+// isSafeInt returns false if the valid number num is an integer outside of
+// -(2^53-1) through 2^53-1.
+func isSafeInt(num string) bool {
+	if num[0] == '-' {
+		num = num[1:]
+	}
+	for i := 0; i < len(num); i++ {
+		if !isNum(num[i]) {
+			return true // not an integer
+		}
+	}
+	const max = "9007199254740991" // 2^53-1
+	return len(num) < len(max) || len(num) == len(max) && num <= max
+}
+
+// isHugeNum returns whether the valid number num is too large in magnitude for
+// a float64.
+func isHugeNum(num string) bool {
+	f, _ := strconv.ParseFloat(num, 64)
+	return math.IsInf(f, 0)
+}
+
+// keySet tracks the keys of an object to detect duplicates. The first few
+// keys are compared directly without allocating; objects with more keys use
+// a map of the unescaped keys.
+type keySet struct {
+	small [8]string
+	n     int
+	big   map[string]struct{}
+}
+
+// add adds the raw (still escaped) key k, returning false if the set already
+// contains the key.
+func (s *keySet) add(k string, scratch *[]byte) bool {
+	if s.n < len(s.small) {
+		for _, seen := range s.small[:s.n] {
+			if unescapedEqual(seen, k) {
+				return false
+			}
+		}
+		s.small[s.n] = k
+		s.n++
+		return true
+	}
+
+	if s.big == nil {
+		s.big = make(map[string]struct{}, 2*len(s.small))
+		for _, seen := range s.small {
+			s.big[string(appendUnescaped((*scratch)[:0], seen))] = struct{}{}
+		}
+	}
+	*scratch = appendUnescaped((*scratch)[:0], k)
+	if _, exists := s.big[string(*scratch)]; exists {
+		return false
+	}
+	s.big[string(*scratch)] = struct{}{}
+	return true
+}
+
+// has returns whether the set contains the raw (still escaped) key k.
+func (s *keySet) has(k string, scratch *[]byte) bool {
+	if s.big == nil {
+		for _, seen := range s.small[:s.n] {
+			if unescapedEqual(seen, k) {
+				return true
+			}
+		}
+		return false
+	}
+	*scratch = appendUnescaped((*scratch)[:0], k)
+	_, exists := s.big[string(*scratch)]
+	return exists
+}
+
+func unsafeString(b []byte) string {
+	return *(*string)(unsafe.Pointer(&b))
+}
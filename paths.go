@@ -0,0 +1,207 @@
+package chkjson
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"unsafe"
+)
+
+// Paths is a compiled set of paths into JSON values, as used by
+// AppendCompactProject. A Paths is safe for concurrent use once compiled.
+type Paths struct {
+	root pathNode
+}
+
+// pathNode is one step along compiled paths. If end is true, a path ends here
+// and the whole value is selected; otherwise, keys selects object members and
+// elems, if non-nil, selects array elements.
+type pathNode struct {
+	end   bool
+	keys  []pathKey
+	elems *pathNode
+}
+
+type pathKey struct {
+	key  string
+	node *pathNode
+}
+
+// CompilePaths compiles paths for selecting parts of JSON values.
+//
+// A path is a sequence of object keys separated by dots, where [*] selects
+// every element of an array: for example, user.id selects the id member of
+// the user member of the top level object, and events[*].ts selects the ts
+// member of every object in the events array. A path may begin with [*] if
+// the top level value is an array, and the empty path selects everything. A
+// backslash escapes the following character in a key, such that a\.b is the
+// single key "a.b".
+//
+// Keys are compared against object keys once those are unescaped. If one path
+// is a prefix of another, the shorter path wins. This returns an error if no
+// paths are given, as projecting with no paths would select nothing.
+func CompilePaths(paths ...string) (*Paths, error) {
+	if len(paths) == 0 {
+		return nil, errors.New("chkjson: no paths to compile")
+	}
+	p := new(Paths)
+	var key []byte
+	for _, path := range paths {
+		n := &p.root
+		for i := 0; i < len(path); {
+			switch {
+			case path[i] == '[':
+				if !strings.HasPrefix(path[i:], "[*]") {
+					return nil, pathError(path, i, "expected [*]")
+				}
+				if n.elems == nil {
+					n.elems = new(pathNode)
+				}
+				n = n.elems
+				i += len("[*]")
+				continue
+			case i > 0 && path[i] != '.':
+				return nil, pathError(path, i, "expected '.' or [*]")
+			case i > 0:
+				i++ // skip the dot
+			}
+
+			key = key[:0]
+			for ; i < len(path) && path[i] != '.' && path[i] != '['; i++ {
+				if path[i] == '\\' {
+					if i++; i == len(path) {
+						return nil, pathError(path, i, "expected character after backslash")
+					}
+				}
+				key = append(key, path[i])
+			}
+			if len(key) == 0 {
+				return nil, pathError(path, i, "expected key")
+			}
+			n = n.child(string(key))
+		}
+		n.end = true
+	}
+	return p, nil
+}
+
+func pathError(path string, at int, why string) error {
+	return errors.New("chkjson: invalid path " + strconv.Quote(path) + " at offset " + strconv.Itoa(at) + ": " + why)
+}
+
+// child returns the node for key below n, adding it if necessary.
+func (n *pathNode) child(key string) *pathNode {
+	for _, k := range n.keys {
+		if k.key == key {
+			return k.node
+		}
+	}
+	c := new(pathNode)
+	n.keys = append(n.keys, pathKey{key, c})
+	return c
+}
+
+// lookup returns the node for the raw (still escaped) key k below n, or nil.
+func (n *pathNode) lookup(k string) *pathNode {
+	for _, key := range n.keys {
+		if unescapedIs(k, key.key) {
+			return key.node
+		}
+	}
+	return nil
+}
+
+// AppendCompactProject is like AppendCompact, but only keeps the parts of src
+// selected by paths, as compiled by CompilePaths. Everything else is validated
+// as quickly as Valid does, but is otherwise skipped.
+//
+// Object members are kept if their key is along a path, and array elements
+// are kept if [*] is along a path. A value along a path whose type does not
+// match the path, such as a number where a path expects an object, is dropped
+// as if it were not selected, although objects and arrays that are selected
+// but end up empty are kept. If the top level value itself does not match, or
+// if paths is nil, the output is null. For example, projecting {"user": {"id": 1, "name": "x"}, "n": 2,
+// "events": [{"ts": 3, "x": 4}, 5]} with user.id and events[*].ts results in
+// {"user":{"id":1},"events":[{"ts":3}]}.
+//
+// This function assumes and returns ownership of dst. If src is invalid, this
+// will return nil. Once paths is compiled, this does not allocate beyond
+// growing dst, and as projecting only shrinks the output, it is valid to pass
+// (src[:0], src) to this function to project in place.
+func AppendCompactProject(dst, src []byte, paths *Paths) ([]byte, bool) {
+	return AppendCompactProjectString(dst, *(*string)(unsafe.Pointer(&src)), paths)
+}
+
+// AppendCompactProjectString is exactly like AppendCompactProject, but for
+// compacting strings.
+func AppendCompactProjectString(dst []byte, src string, paths *Paths) ([]byte, bool) {
+	p := projector{walker{in: src, dst: dst}}
+	root := new(pathNode) // nil paths select nothing
+	if paths != nil {
+		root = &paths.root
+	}
+	start := len(dst)
+	at, ok := p.value(0, root)
+	if !ok || skipSpace(src, at) != len(src) {
+		return nil, false
+	}
+	if len(p.dst) == start {
+		p.dst = append(p.dst, "null"...)
+	}
+	return p.dst, true
+}
+
+// projector compacts like packAny, but only writes values along paths. Values
+// not along paths are skipped with any.
+type projector struct {
+	walker
+}
+
+// value writes nothing if the value at in[at] does not match n.
+func (p *projector) value(at int, n *pathNode) (int, bool) {
+	in := p.in
+	if n.end {
+		var ok bool
+		p.dst, at, ok = packAny(p.dst, in, at)
+		return at, ok
+	}
+	if at = skipSpace(in, at); at == len(in) {
+		return at, false
+	}
+	switch {
+	case in[at] == '{' && len(n.keys) > 0:
+		return p.obj(at, n)
+	case in[at] == '[' && n.elems != nil:
+		return p.arr(at, n.elems)
+	default:
+		return any(in, at)
+	}
+}
+
+func (p *projector) obj(at int, n *pathNode) (int, bool) {
+	return p.walkObj(at, func(key string, at int) (int, bool, bool) {
+		child := n.lookup(key)
+		if child == nil {
+			at, ok := any(p.in, at)
+			return at, false, ok
+		}
+		return p.member(at, child)
+	})
+}
+
+// member writes the value at in[at] along with the colon before it, keeping
+// them if the value matches n.
+func (p *projector) member(at int, n *pathNode) (int, bool, bool) {
+	p.dst = append(p.dst, ':')
+	mark := len(p.dst)
+	at, ok := p.value(at, n)
+	return at, len(p.dst) > mark, ok
+}
+
+func (p *projector) arr(at int, elems *pathNode) (int, bool) {
+	return p.walkArr(at, func(at int) (int, bool, bool) {
+		mark := len(p.dst)
+		at, ok := p.value(at, elems)
+		return at, len(p.dst) > mark, ok
+	})
+}
@@ -0,0 +1,109 @@
+package chkjson
+
+import (
+	"testing"
+)
+
+func TestCompilePaths(t *testing.T) {
+	for _, path := range []string{
+		"",
+		"a",
+		"a.b",
+		"[*]",
+		"[*][*].a",
+		"a[*].b[*]",
+		`a\.b`,
+		`a\[b`,
+	} {
+		if _, err := CompilePaths(path); err != nil {
+			t.Errorf("%q: unexpected err %v", path, err)
+		}
+	}
+	for _, path := range []string{
+		".",
+		"a.",
+		".a",
+		"a..b",
+		"a[0]",
+		"a[*",
+		"a[*]b",
+		`a\`,
+	} {
+		if _, err := CompilePaths(path); err == nil {
+			t.Errorf("%q: unexpectedly compiled", path)
+		}
+	}
+	if _, err := CompilePaths(); err == nil {
+		t.Error("no paths unexpectedly compiled")
+	}
+}
+
+func TestAppendCompactProject(t *testing.T) {
+	for _, test := range []struct {
+		in    string
+		paths []string
+		exp   string // empty if invalid
+	}{
+		{` { "a" : [ 1 , 2 ] } `, []string{""}, `{"a":[1,2]}`},
+		{`{"a": 1, "b": 2, "c": 3}`, []string{"c", "a"}, `{"a":1,"c":3}`},
+		{`{"a": 1, "b": 2}`, []string{"x"}, `{}`},
+		{`{"a": {"b": [1, {"c": 2}]}, "x": {"y": 1}}`, []string{"a"}, `{"a":{"b":[1,{"c":2}]}}`},
+		{
+			`{"user": {"id": 1, "name": "x"}, "n": 2, "events": [{"ts": 3, "x": 4}, 5, {"ts": [6]}]}`,
+			[]string{"user.id", "events[*].ts"},
+			`{"user":{"id":1},"events":[{"ts":3},{"ts":[6]}]}`,
+		},
+		{`{"user": 1}`, []string{"user.id"}, `{}`},
+		{`{"user": {"name": 1}}`, []string{"user.id"}, `{"user":{}}`},
+		{`{"a": {"b": 1, "c": 2}}`, []string{"a.b", "a"}, `{"a":{"b":1,"c":2}}`},
+		{`{"a": {"b": 1, "c": 2, "d": 3}}`, []string{"a.b", "a.c"}, `{"a":{"b":1,"c":2}}`},
+		{`{"a": [1, {"b": 2}], "c": 3}`, []string{"a[*]"}, `{"a":[1,{"b":2}]}`},
+		{`[[1, {"a": 1}], {"a": 2, "b": 3}]`, []string{"[*].a"}, `[{"a":2}]`},
+		{`[[1, {"a": 1}], {"a": 2, "b": 3}]`, []string{"[*][*].a"}, `[[{"a":1}]]`},
+		{`{"\u0069d": 1, "a.b": 2}`, []string{"id", `a\.b`}, `{"\u0069d":1,"a.b":2}`},
+		{`{"a": 1, "a": 2}`, []string{"a"}, `{"a":1,"a":2}`},
+		{`5`, []string{"a"}, `null`},
+		{`[]`, []string{"a"}, `null`},
+		{`{}`, []string{"a"}, `{}`},
+
+		{`{"a": 1, "b": [1,]}`, []string{"a"}, ``},
+		{`{"a": 1, "b": tru}`, []string{"a"}, ``},
+		{`{"a" 1}`, []string{"b"}, ``},
+		{`{"b" 1}`, []string{"b"}, ``},
+		{`{"a": [1, 2,]}`, []string{"a[*]"}, ``},
+		{`{"a": 1,}`, []string{"a"}, ``},
+		{`[1, 2,]`, []string{"[*]"}, ``},
+		{`{"a": 1} 1`, []string{"a"}, ``},
+	} {
+		paths, err := CompilePaths(test.paths...)
+		if err != nil {
+			t.Fatalf("%q: unexpected err %v", test.paths, err)
+		}
+		got, ok := AppendCompactProjectString(nil, test.in, paths)
+		if ok != (test.exp != "") || string(got) != test.exp {
+			t.Errorf("«%s» %q: got «%s» (ok? %v) != exp «%s»", test.in, test.paths, got, ok, test.exp)
+		}
+		inplace := []byte(test.in)
+		inplace, oki := AppendCompactProject(inplace[:0], inplace, paths)
+		if oki != ok || ok && string(inplace) != string(got) {
+			t.Errorf("«%s» %q: got «%s» (ok? %v) in place != «%s» (ok? %v)", test.in, test.paths, inplace, oki, got, ok)
+		}
+	}
+
+	// Nil paths select nothing, but still validate.
+	if got, ok := AppendCompactProjectString(nil, `{"a": 1}`, nil); !ok || string(got) != "null" {
+		t.Errorf("nil paths: got «%s» (ok? %v) != exp «null»", got, ok)
+	}
+	if _, ok := AppendCompactProjectString(nil, `{"a": 1,}`, nil); ok {
+		t.Error("nil paths: invalid input unexpectedly valid")
+	}
+
+	paths, _ := CompilePaths("user.id", "events[*].ts")
+	in := []byte(`{"user": {"id": 1, "name": "x"}, "events": [{"ts": 3, "x": 4}]}`)
+	dst := make([]byte, 0, len(in))
+	if allocs := testing.AllocsPerRun(100, func() {
+		AppendCompactProject(dst[:0], in, paths)
+	}); allocs != 0 {
+		t.Errorf("got %v allocs != exp 0", allocs)
+	}
+}
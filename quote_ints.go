@@ -0,0 +1,154 @@
+package chkjson
+
+import (
+	"unsafe"
+)
+
+// AppendCompactQuoteBigInts is like AppendCompact, but also quotes integers
+// outside of -(2^53-1) through 2^53-1, turning them into strings: for example,
+// 12345678901234567890 becomes "12345678901234567890". JavaScript and other
+// consumers that parse numbers as IEEE 754 doubles silently lose precision on
+// such integers, but keep strings exactly. Only numbers written as integers,
+// that is, without a fraction or exponent, are quoted.
+//
+// If keys are given, only integers that are the values of object members with
+// one of the keys, or that are within arrays that are such values, are quoted.
+// Keys are compared against object keys once those are unescaped. For example,
+// with the key "id", both {"id": 12345678901234567890} and
+// {"id": [12345678901234567890]} are quoted, but {"id": {"x":
+// 12345678901234567890}} is not.
+//
+// This function assumes and returns ownership of dst. If src is invalid, this
+// will return nil. Quoting makes the output longer than the input, meaning dst
+// must not share memory with src.
+func AppendCompactQuoteBigInts(dst, src []byte, keys ...string) ([]byte, bool) {
+	return AppendCompactQuoteBigIntsString(dst, *(*string)(unsafe.Pointer(&src)), keys...)
+}
+
+// AppendCompactQuoteBigIntsString is exactly like AppendCompactQuoteBigInts,
+// but for compacting strings.
+func AppendCompactQuoteBigIntsString(dst []byte, src string, keys ...string) ([]byte, bool) {
+	q := intQuoter{in: src, dst: dst, keys: keys}
+	at, ok := q.value(0, len(keys) == 0)
+	if !ok || skipSpace(src, at) != len(src) {
+		return nil, false
+	}
+	return q.dst, true
+}
+
+// intQuoter compacts like packAny, but quotes unsafe integers that are within
+// values it is told to quote.
+type intQuoter struct {
+	in   string
+	dst  []byte
+	keys []string
+}
+
+func (q *intQuoter) value(at int, quote bool) (int, bool) {
+	in := q.in
+	if at = skipSpace(in, at); at == len(in) {
+		return at, false
+	}
+	var ok bool
+	switch in[at] {
+	case '{':
+		return q.obj(at)
+	case '[':
+		return q.arr(at, quote)
+	case '-', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
+		// We pack the number with packAny and then quote it in dst,
+		// shifting it over by one, if necessary.
+		start := len(q.dst)
+		if q.dst, at, ok = packAny(q.dst, in, at); !ok {
+			return at, false
+		}
+		if quote && !isSafeInt(unsafeString(q.dst[start:])) {
+			q.dst = append(q.dst, '"', '"')
+			copy(q.dst[start+1:], q.dst[start:len(q.dst)-2])
+			q.dst[start] = '"'
+		}
+		return at, true
+	default:
+		q.dst, at, ok = packAny(q.dst, in, at)
+		return at, ok
+	}
+}
+
+func (q *intQuoter) obj(at int) (int, bool) {
+	in := q.in
+	q.dst = append(q.dst, '{')
+	if at = skipSpace(in, at+1); at < len(in) && in[at] == '}' {
+		q.dst = append(q.dst, '}')
+		return at + 1, true
+	}
+
+	var ok bool
+	for {
+		if at == len(in) || in[at] != '"' {
+			return at, false
+		}
+		keyStart := len(q.dst)
+		if q.dst, at, ok = packAny(q.dst, in, at); !ok {
+			return at, false
+		}
+		quote := len(q.keys) == 0
+		key := unsafeString(q.dst[keyStart+1 : len(q.dst)-1])
+		for _, k := range q.keys {
+			if unescapedIs(key, k) {
+				quote = true
+				break
+			}
+		}
+
+		if at = skipSpace(in, at); at == len(in) || in[at] != ':' {
+			return at, false
+		}
+		q.dst = append(q.dst, ':')
+		if at, ok = q.value(at+1, quote); !ok {
+			return at, false
+		}
+
+		if at = skipSpace(in, at); at == len(in) {
+			return at, false
+		}
+		switch in[at] {
+		case ',':
+			q.dst = append(q.dst, ',')
+			at = skipSpace(in, at+1)
+		case '}':
+			q.dst = append(q.dst, '}')
+			return at + 1, true
+		default:
+			return at, false
+		}
+	}
+}
+
+func (q *intQuoter) arr(at int, quote bool) (int, bool) {
+	in := q.in
+	q.dst = append(q.dst, '[')
+	if at = skipSpace(in, at+1); at < len(in) && in[at] == ']' {
+		q.dst = append(q.dst, ']')
+		return at + 1, true
+	}
+
+	var ok bool
+	for {
+		if at, ok = q.value(at, quote); !ok {
+			return at, false
+		}
+		if at = skipSpace(in, at); at == len(in) {
+			return at, false
+		}
+		switch in[at] {
+		case ',':
+			q.dst = append(q.dst, ',')
+			at++
+		case ']':
+			q.dst = append(q.dst, ']')
+			return at + 1, true
+		default:
+			return at, false
+		}
+	}
+}
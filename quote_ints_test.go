@@ -0,0 +1,55 @@
+package chkjson
+
+import (
+	"testing"
+)
+
+func TestAppendCompactQuoteBigInts(t *testing.T) {
+	for _, test := range []struct {
+		in   string
+		keys []string
+		exp  string // empty if invalid
+	}{
+		{`12345678901234567890`, nil, `"12345678901234567890"`},
+		{`-9007199254740992`, nil, `"-9007199254740992"`},
+		{`9007199254740991`, nil, `9007199254740991`},
+		{`1.2345678901234567890`, nil, `1.2345678901234567890`},
+		{`12345678901234567890e0`, nil, `12345678901234567890e0`},
+		{`[0, -12345678901234567890, 2.5, 12345678901234567890]`, nil, `[0,"-12345678901234567890",2.5,"12345678901234567890"]`},
+		{
+			` { "a" : [ 1 , 12345678901234567890 ] , "b" : { "c" : -12345678901234567890 } } `,
+			nil,
+			`{"a":[1,"12345678901234567890"],"b":{"c":"-12345678901234567890"}}`,
+		},
+		{
+			`{"id": 12345678901234567890, "n": 12345678901234567890, "ids": [12345678901234567890, {"id": 12345678901234567890, "x": 12345678901234567890}]}`,
+			[]string{"id", "ids"},
+			`{"id":"12345678901234567890","n":12345678901234567890,"ids":["12345678901234567890",{"id":"12345678901234567890","x":12345678901234567890}]}`,
+		},
+		{`{"\u0069d": 12345678901234567890}`, []string{"id"}, `{"\u0069d":"12345678901234567890"}`},
+		{`{"id": {"x": 12345678901234567890}}`, []string{"id"}, `{"id":{"x":12345678901234567890}}`},
+		{`{"a\"b": 12345678901234567890}`, []string{`a"b`}, `{"a\"b":"12345678901234567890"}`},
+		{`12345678901234567890`, []string{"id"}, `12345678901234567890`},
+
+		{`[12345678901234567890`, nil, ``},
+		{`{"id": 1,}`, []string{"id"}, ``},
+		{`12345678901234567890 1`, nil, ``},
+	} {
+		got, ok := AppendCompactQuoteBigIntsString(nil, test.in, test.keys...)
+		if ok != (test.exp != "") || string(got) != test.exp {
+			t.Errorf("«%s» %q: got «%s» (ok? %v) != exp «%s»", test.in, test.keys, got, ok, test.exp)
+		}
+		gotb, okb := AppendCompactQuoteBigInts([]byte("x"), []byte(test.in), test.keys...)
+		if okb != ok || ok && string(gotb) != "x"+string(got) {
+			t.Errorf("«%s» %q: got «%s» (ok? %v) as bytes != «%s» (ok? %v)", test.in, test.keys, gotb, okb, got, ok)
+		}
+	}
+
+	for i := 0; i < 3; i++ {
+		big := genBig()
+		exp, _ := AppendCompact(nil, big)
+		if got, ok := AppendCompactQuoteBigInts(nil, big); !ok || string(got) != string(exp) {
+			t.Errorf("genBig: got ok? %v, equal? %v", ok, string(got) == string(exp))
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package chkjson
+
+import (
+	"errors"
+	"strconv"
+	"unsafe"
+)
+
+// Redaction is a compiled set of values to redact, and what to redact them
+// with, as used by AppendCompactRedact. A Redaction is safe for concurrent use
+// once compiled.
+type Redaction struct {
+	keys  []string
+	paths *Paths
+	with  string
+}
+
+// CompileRedaction compiles which values to redact and what to replace them
+// with.
+//
+// Values of object members with one of keys are redacted wherever they are,
+// no matter how deeply nested. Keys are compared against object keys once
+// those are unescaped. Values at the end of one of paths, which use the syntax
+// of CompilePaths, are redacted as well.
+//
+// Redacted values are replaced with the JSON in with, for example
+// "[REDACTED]" including the quotes, which is compacted once here. If with is
+// empty, redacted object members and array elements are removed entirely.
+// This returns an error if a path is invalid or if with is not empty and not
+// valid JSON.
+func CompileRedaction(keys, paths []string, with string) (*Redaction, error) {
+	r := &Redaction{keys: append([]string(nil), keys...)}
+	if len(paths) > 0 {
+		var err error
+		if r.paths, err = CompilePaths(paths...); err != nil {
+			return nil, err
+		}
+	}
+	if with != "" {
+		w, ok := AppendCompactString(nil, with)
+		if !ok {
+			return nil, errors.New("chkjson: invalid redaction replacement " + strconv.Quote(with))
+		}
+		r.with = string(w)
+	}
+	return r, nil
+}
+
+// AppendCompactRedact is like AppendCompact, but also redacts values as
+// compiled into r by CompileRedaction. Redacted objects and arrays are
+// replaced whole. Redacted values are still validated, but everything else is
+// compacted as usual.
+//
+// For example, redacting {"user": "x", "password": "hunter2", "card":
+// {"number": 1234, "exp": "01/30"}} with the key password, the path
+// card.number, and "[REDACTED]" results in
+// {"user":"x","password":"[REDACTED]","card":{"number":"[REDACTED]","exp":"01/30"}}.
+// If the top level value itself is redacted and values are removed rather
+// than replaced, the output is null.
+//
+// This function assumes and returns ownership of dst. If src is invalid, this
+// will return nil. Replacing values can make the output longer than the
+// input, meaning dst must not share memory with src unless r removes values,
+// in which case redacting only shrinks the output and it is valid to pass
+// (src[:0], src) to this function.
+func AppendCompactRedact(dst, src []byte, r *Redaction) ([]byte, bool) {
+	return AppendCompactRedactString(dst, *(*string)(unsafe.Pointer(&src)), r)
+}
+
+// AppendCompactRedactString is exactly like AppendCompactRedact, but for
+// compacting strings.
+func AppendCompactRedactString(dst []byte, src string, r *Redaction) ([]byte, bool) {
+	d := redactor{walker{in: src, dst: dst}, r}
+	var root *pathNode // nil if there are no paths
+	if r.paths != nil {
+		root = &r.paths.root
+	}
+	var at int
+	var ok bool
+	if root != nil && root.end {
+		start := len(dst)
+		at, ok = d.redact(0)
+		if ok && len(d.dst) == start {
+			d.dst = append(d.dst, "null"...)
+		}
+	} else {
+		at, ok = d.value(0, root)
+	}
+	if !ok || skipSpace(src, at) != len(src) {
+		return nil, false
+	}
+	return d.dst, true
+}
+
+// redactor compacts like packAny, but redacts values along the end of paths
+// or with matching keys. The current path node, if any, is threaded through
+// objects and arrays.
+type redactor struct {
+	walker
+	r *Redaction
+}
+
+// redact validates the value at in[at] and writes r.with in its place.
+func (d *redactor) redact(at int) (int, bool) {
+	d.dst = append(d.dst, d.r.with...)
+	return any(d.in, at)
+}
+
+func (d *redactor) value(at int, n *pathNode) (int, bool) {
+	in := d.in
+	if at = skipSpace(in, at); at == len(in) {
+		return at, false
+	}
+	var ok bool
+	switch in[at] {
+	case '{':
+		return d.obj(at, n)
+	case '[':
+		return d.arr(at, n)
+	default:
+		d.dst, at, ok = packAny(d.dst, in, at)
+		return at, ok
+	}
+}
+
+func (d *redactor) obj(at int, n *pathNode) (int, bool) {
+	return d.walkObj(at, func(key string, at int) (int, bool, bool) {
+		var child *pathNode
+		if n != nil {
+			child = n.lookup(key)
+		}
+		redact := child != nil && child.end
+		for i := 0; i < len(d.r.keys) && !redact; i++ {
+			redact = unescapedIs(key, d.r.keys[i])
+		}
+		d.dst = append(d.dst, ':')
+		if !redact {
+			at, ok := d.value(at, child)
+			return at, true, ok
+		}
+		return d.member(at)
+	})
+}
+
+// member redacts the value at in[at], the value of an object member or an
+// array element, which is only kept if there is something to redact it with.
+func (d *redactor) member(at int) (int, bool, bool) {
+	at, ok := d.redact(at)
+	return at, d.r.with != "", ok
+}
+
+func (d *redactor) arr(at int, n *pathNode) (int, bool) {
+	var elems *pathNode
+	if n != nil {
+		elems = n.elems
+	}
+	if elems != nil && elems.end {
+		return d.walkArr(at, d.member)
+	}
+	return d.walkArr(at, func(at int) (int, bool, bool) {
+		at, ok := d.value(at, elems)
+		return at, true, ok
+	})
+}
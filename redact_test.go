@@ -0,0 +1,95 @@
+package chkjson
+
+import (
+	"testing"
+)
+
+func TestCompileRedaction(t *testing.T) {
+	for _, with := range []string{`[REDACTED]`, `{`, `"a`, `1 2`, ` `} {
+		if _, err := CompileRedaction([]string{"a"}, nil, with); err == nil {
+			t.Errorf("with %q: unexpectedly compiled", with)
+		}
+	}
+	if _, err := CompileRedaction(nil, []string{"a."}, ""); err == nil {
+		t.Error("invalid path unexpectedly compiled")
+	}
+
+	r, err := CompileRedaction([]string{"a"}, nil, ` [ "x" , 1 ] `)
+	if err != nil {
+		t.Fatalf("unexpected err %v", err)
+	}
+	if got, ok := AppendCompactRedactString(nil, `{"a": 1}`, r); !ok || string(got) != `{"a":["x",1]}` {
+		t.Errorf("got «%s» (ok? %v) != exp «%s»", got, ok, `{"a":["x",1]}`)
+	}
+}
+
+func TestAppendCompactRedact(t *testing.T) {
+	const redacted = `"[REDACTED]"`
+	for _, test := range []struct {
+		in    string
+		keys  []string
+		paths []string
+		with  string
+		exp   string // empty if invalid
+	}{
+		{` { "a" : [ 1 , 2 ] } `, nil, nil, redacted, `{"a":[1,2]}`},
+		{
+			`{"user": "x", "password": "hunter2", "card": {"number": 1234, "exp": "01/30"}}`,
+			[]string{"password"},
+			[]string{"card.number"},
+			redacted,
+			`{"user":"x","password":"[REDACTED]","card":{"number":"[REDACTED]","exp":"01/30"}}`,
+		},
+		{
+			`{"user": "x", "password": "hunter2", "card": {"number": 1234, "exp": "01/30"}}`,
+			[]string{"password"},
+			[]string{"card.number"},
+			``,
+			`{"user":"x","card":{"exp":"01/30"}}`,
+		},
+		{
+			`{"users": [{"ssn": {"a": [1, 2]}, "name": "x"}, {"n": {"ssn": [1]}}]}`,
+			[]string{"ssn"},
+			nil,
+			redacted,
+			`{"users":[{"ssn":"[REDACTED]","name":"x"},{"n":{"ssn":"[REDACTED]"}}]}`,
+		},
+		{`{"ssn": 1, "ssn": 2, "a": 3}`, []string{"ssn"}, nil, ``, `{"a":3}`},
+		{`{"a": 1, "ssn": 2, "ssn": 3}`, []string{"ssn"}, nil, ``, `{"a":1}`},
+		{`{"\u0073sn": 1}`, []string{"ssn"}, nil, `null`, `{"\u0073sn":null}`},
+		{`{"a": {"b": 1}, "b": 2}`, nil, []string{"a.b"}, `0`, `{"a":{"b":0},"b":2}`},
+		{`{"a": [{"b": 1}, 2, {"c": 3}]}`, nil, []string{"a[*].b"}, `0`, `{"a":[{"b":0},2,{"c":3}]}`},
+		{`{"a": [1, [2], {"c": 3}]}`, nil, []string{"a[*]"}, `0`, `{"a":[0,0,0]}`},
+		{`{"a": [1, [2], {"c": 3}], "b": 4}`, nil, []string{"a[*]"}, ``, `{"a":[],"b":4}`},
+		{`[1, 2]`, nil, []string{""}, redacted, redacted},
+		{`[1, 2]`, nil, []string{""}, ``, `null`},
+		{`"password"`, []string{"password"}, nil, redacted, `"password"`},
+
+		{`{"password": [1,]}`, []string{"password"}, nil, redacted, ``},
+		{`{"password": 1,}`, []string{"password"}, nil, ``, ``},
+		{`{"password" 1}`, []string{"password"}, nil, ``, ``},
+		{`{"a": [1, 2,]}`, nil, []string{"a[*]"}, ``, ``},
+		{`[1, 2] 3`, nil, []string{""}, ``, ``},
+		{`{"a" 1}`, []string{"a"}, nil, ``, ``},
+	} {
+		r, err := CompileRedaction(test.keys, test.paths, test.with)
+		if err != nil {
+			t.Fatalf("%q %q: unexpected err %v", test.keys, test.paths, err)
+		}
+		got, ok := AppendCompactRedactString(nil, test.in, r)
+		if ok != (test.exp != "") || string(got) != test.exp {
+			t.Errorf("«%s» %q %q: got «%s» (ok? %v) != exp «%s»", test.in, test.keys, test.paths, got, ok, test.exp)
+		}
+		gotb, okb := AppendCompactRedact([]byte("x"), []byte(test.in), r)
+		if okb != ok || ok && string(gotb) != "x"+string(got) {
+			t.Errorf("«%s»: got «%s» (ok? %v) as bytes != «%s» (ok? %v)", test.in, gotb, okb, got, ok)
+		}
+		if test.with == "" {
+			inplace := []byte(test.in)
+			inplace, oki := AppendCompactRedact(inplace[:0], inplace, r)
+			if oki != ok || ok && string(inplace) != string(got) {
+				t.Errorf("«%s»: got «%s» (ok? %v) in place != «%s» (ok? %v)", test.in, inplace, oki, got, ok)
+			}
+		}
+	}
+}
@@ -0,0 +1,231 @@
+package chkjson
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"unsafe"
+)
+
+// Renames is a compiled table of object key renames, as used by
+// AppendCompactRename. A Renames is safe for concurrent use once compiled.
+type Renames struct {
+	keys  []keyRename // sorted by from
+	paths renameNode
+}
+
+// keyRename renames the unescaped key from to the quoted key to.
+type keyRename struct {
+	from, to string
+}
+
+// renameNode is one step along compiled rename paths, mirroring pathNode. If
+// to is non-empty, a path ends here and the key is renamed to the quoted to.
+// Keys are sorted so that they can be searched.
+type renameNode struct {
+	to    string
+	keys  []renameKey
+	elems *renameNode
+}
+
+type renameKey struct {
+	key  string
+	node *renameNode
+}
+
+// CompileRenames compiles a table of object key renames. Each entry in keys
+// renames object keys equal to the entry's key to the entry's value, no
+// matter where the objects are. Each entry in paths renames the key at the
+// end of the entry's path, which uses the syntax of CompilePaths and must end
+// in a key, to the entry's value. A path rename takes precedence over a key
+// rename of the same key.
+//
+// Keys, keys at the end of paths, and new keys are all plain text, not JSON:
+// they are matched against object keys once those are unescaped, and new keys
+// are escaped when written. Thus, no two entries in keys can rename the same
+// key. This returns an error if a path is invalid or if two paths rename the
+// same key, such as a\b and ab.
+//
+// For example, the key rename userId to user_id renames {"userId": 1} and
+// {"a": [{"userId": 1}]} alike, whereas the path rename a[*].userId to
+// user_id only renames the latter.
+func CompileRenames(keys, paths map[string]string) (*Renames, error) {
+	r := new(Renames)
+	for from, to := range keys {
+		r.keys = append(r.keys, keyRename{from, quoteKey(to)})
+	}
+	sort.Slice(r.keys, func(i, j int) bool { return r.keys[i].from < r.keys[j].from })
+
+	for path, to := range paths {
+		p, err := CompilePaths(path)
+		if err != nil {
+			return nil, err
+		}
+		// A single compiled path is a chain of nodes, each with one
+		// key or [*], which we follow into our own nodes.
+		n, isKey := &r.paths, false
+		for c := &p.root; !c.end; {
+			if c.elems != nil {
+				if n.elems == nil {
+					n.elems = new(renameNode)
+				}
+				n, c, isKey = n.elems, c.elems, false
+				continue
+			}
+			n, c, isKey = n.child(c.keys[0].key), c.keys[0].node, true
+		}
+		if !isKey {
+			return nil, pathError(path, len(path), "expected path to end in a key")
+		}
+		if n.to != "" {
+			return nil, errors.New("chkjson: path " + strconv.Quote(path) + " renames a key that another path renames")
+		}
+		n.to = quoteKey(to)
+	}
+	return r, nil
+}
+
+// child returns the node for key below n, adding it if necessary.
+func (n *renameNode) child(key string) *renameNode {
+	i := sort.Search(len(n.keys), func(i int) bool { return n.keys[i].key >= key })
+	if i < len(n.keys) && n.keys[i].key == key {
+		return n.keys[i].node
+	}
+	c := new(renameNode)
+	n.keys = append(n.keys, renameKey{})
+	copy(n.keys[i+1:], n.keys[i:])
+	n.keys[i] = renameKey{key, c}
+	return c
+}
+
+// lookup returns the node for the unescaped key k below n, or nil.
+func (n *renameNode) lookup(k string) *renameNode {
+	i := sort.Search(len(n.keys), func(i int) bool { return n.keys[i].key >= k })
+	if i < len(n.keys) && n.keys[i].key == k {
+		return n.keys[i].node
+	}
+	return nil
+}
+
+// rename returns the quoted key that the unescaped key k is renamed to no
+// matter where it is, or an empty string.
+func (r *Renames) rename(k string) string {
+	i := sort.Search(len(r.keys), func(i int) bool { return r.keys[i].from >= k })
+	if i < len(r.keys) && r.keys[i].from == k {
+		return r.keys[i].to
+	}
+	return ""
+}
+
+// quoteKey returns k quoted as a JSON string.
+func quoteKey(k string) string {
+	b := append(EscapeString([]byte{'"'}, k), '"')
+	return string(b)
+}
+
+// AppendCompactRename is like AppendCompact, but also renames object keys as
+// compiled into r by CompileRenames. Object keys are matched once unescaped,
+// meaning "\u0075serId" is renamed just as "userId" is, and renamed keys are
+// written escaped as Escape does.
+//
+// Renaming fails if it would leave an object with a renamed key that
+// duplicates another key, such as renaming userId to user_id in
+// {"userId": 1, "user_id": 2}: which value is meant is ambiguous, and many
+// consumers silently keep only one. This includes renaming a key that src
+// already duplicates, such as userId in {"userId": 1, "userId": 2}, as both
+// members would be renamed. Duplicate keys that are in src and are not renamed
+// are kept as is. Objects with up to eight keys are checked without
+// allocating.
+//
+// This function assumes and returns ownership of dst. If src is invalid or a
+// rename collides, this will return nil. Renaming can make the output longer
+// than the input, meaning dst must not share memory with src.
+func AppendCompactRename(dst, src []byte, r *Renames) ([]byte, bool) {
+	return AppendCompactRenameString(dst, *(*string)(unsafe.Pointer(&src)), r)
+}
+
+// AppendCompactRenameString is exactly like AppendCompactRename, but for
+// compacting strings.
+func AppendCompactRenameString(dst []byte, src string, r *Renames) ([]byte, bool) {
+	n := renamer{walker: walker{in: src, dst: dst}, r: r}
+	at, ok := n.value(0, &r.paths)
+	if !ok || skipSpace(src, at) != len(src) {
+		return nil, false
+	}
+	return n.dst, true
+}
+
+// renamer compacts like packAny, but renames keys. As in redactor, the current
+// path node, if any, is threaded through objects and arrays.
+type renamer struct {
+	walker
+	r       *Renames
+	scratch []byte // for unescaping keys and for keySet
+}
+
+func (n *renamer) value(at int, node *renameNode) (int, bool) {
+	in := n.in
+	if at = skipSpace(in, at); at == len(in) {
+		return at, false
+	}
+	var ok bool
+	switch in[at] {
+	case '{':
+		return n.obj(at, node)
+	case '[':
+		var elems *renameNode
+		if node != nil {
+			elems = node.elems
+		}
+		return n.walkArr(at, func(at int) (int, bool, bool) {
+			at, ok := n.value(at, elems)
+			return at, true, ok
+		})
+	default:
+		n.dst, at, ok = packAny(n.dst, in, at)
+		return at, ok
+	}
+}
+
+func (n *renamer) obj(at int, node *renameNode) (int, bool) {
+	// We track renamed keys separately from kept keys so that we only
+	// fail on duplicates that renaming creates. As in parser.obj, the
+	// keys we track are in dst, which is not overwritten.
+	var renamed, kept keySet
+	return n.walkObj(at, func(key string, at int) (int, bool, bool) {
+		plain := key
+		if strings.IndexByte(key, '\\') >= 0 {
+			n.scratch = appendUnescaped(n.scratch[:0], key)
+			plain = unsafeString(n.scratch)
+		}
+		var child *renameNode
+		var to string
+		if node != nil {
+			if child = node.lookup(plain); child != nil {
+				to = child.to
+			}
+		}
+		if to == "" {
+			to = n.r.rename(plain)
+		}
+
+		if to != "" {
+			keyStart := len(n.dst) - len(key) - 2
+			n.dst = append(n.dst[:keyStart], to...)
+			key = unsafeString(n.dst[keyStart+1 : len(n.dst)-1])
+			if !renamed.add(key, &n.scratch) || kept.has(key, &n.scratch) {
+				return at, false, false
+			}
+		} else {
+			kept.add(key, &n.scratch)
+			if renamed.has(key, &n.scratch) {
+				return at, false, false
+			}
+		}
+
+		n.dst = append(n.dst, ':')
+		at, ok := n.value(at, child)
+		return at, true, ok
+	})
+}
@@ -0,0 +1,124 @@
+package chkjson
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestCompileRenames(t *testing.T) {
+	for _, path := range []string{"", "[*]", "a[*]", "a.", "a[0]"} {
+		if _, err := CompileRenames(nil, map[string]string{path: "b"}); err == nil {
+			t.Errorf("%q: unexpectedly compiled", path)
+		}
+	}
+
+	// Both paths end at the key ab, and map order would pick the winner.
+	for i := 0; i < 10; i++ {
+		if _, err := CompileRenames(nil, map[string]string{`x.a\b`: "c", "x.ab": "d"}); err == nil {
+			t.Fatal("paths renaming the same key unexpectedly compiled")
+		}
+	}
+
+	// Keys are plain text, meaning the second key has a backslash and
+	// cannot match what the first matches, no matter the map order.
+	keys := map[string]string{"userId": "a", `\u0075serId`: "b"}
+	in := `{"\u0075serId": 1, "\\u0075serId": 2}`
+	for i := 0; i < 10; i++ {
+		r, err := CompileRenames(keys, nil)
+		if err != nil {
+			t.Fatalf("unexpected err %v", err)
+		}
+		if got, ok := AppendCompactRenameString(nil, in, r); !ok || string(got) != `{"a":1,"b":2}` {
+			t.Fatalf("got «%s» (ok? %v) != exp «%s»", got, ok, `{"a":1,"b":2}`)
+		}
+	}
+}
+
+func TestAppendCompactRename(t *testing.T) {
+	var many []string
+	for i := 0; i < 10; i++ {
+		many = append(many, fmt.Sprintf(`"k%d": %d`, i, i))
+	}
+	manyKeys := strings.Join(many, ", ")
+
+	userID := map[string]string{"userId": "user_id"}
+	for _, test := range []struct {
+		keys  map[string]string
+		paths map[string]string
+		in    string
+		exp   string // empty if invalid or if a rename collides
+	}{
+		{nil, nil, ` { "a" : [ 1 , 2 ] } `, `{"a":[1,2]}`},
+		{nil, nil, `{"a": [1 2]}`, ``},
+		{nil, nil, `{} 1`, ``},
+
+		{
+			userID, nil,
+			`{"userId": 1, "a": [{"userId": 2}, {"b": {"userId": 3}}]}`,
+			`{"user_id":1,"a":[{"user_id":2},{"b":{"user_id":3}}]}`,
+		},
+		{userID, nil, `"userId"`, `"userId"`},
+		{userID, nil, `{"a": 1, "a": 2, "userId": 3}`, `{"a":1,"a":2,"user_id":3}`},
+		{userID, nil, `{"\u0075serId": 1}`, `{"user_id":1}`},
+
+		// collisions
+		{userID, nil, `{"userId": 1, "user_id": 2}`, ``},
+		{userID, nil, `{"user_id": 1, "userId": 2}`, ``},
+		{userID, nil, `{"userId": 1, "userId": 2}`, ``},
+		{userID, nil, `{"userId": 1, "\u0075serId": 2}`, ``},
+		{userID, nil, `{"a": {"userId": 1, "user_\u0069d": 2}}`, ``},
+		{userID, nil, `{` + manyKeys + `, "userId": 1, "user_id": 2}`, ``},
+		{userID, nil, `{"userId": 1, ` + manyKeys + `, "user_id": 2}`, ``},
+		{userID, nil, `{"userId": 1, ` + manyKeys + `, "userId": 2}`, ``},
+		{userID, nil, `{"userId": 1,}`, ``},
+		{userID, nil, `{"userId" 1}`, ``},
+
+		{
+			nil, map[string]string{"a[*].userId": "user_id"},
+			`{"userId": 1, "a": [{"userId": 2}, {"b": {"userId": 3}}]}`,
+			`{"userId":1,"a":[{"user_id":2},{"b":{"userId":3}}]}`,
+		},
+		{nil, map[string]string{"a[*].userId": "user_id"}, `{"user_id": 1, "a": [{"userId": 2, "user_id": 3}]}`, ``},
+		{
+			map[string]string{"userId": "uid"}, map[string]string{"a.userId": "user_id"},
+			`{"userId": 1, "a": {"userId": 2}}`,
+			`{"uid":1,"a":{"user_id":2}}`,
+		},
+		{
+			map[string]string{"b": "a"}, map[string]string{"a": "x", "a.a": "y"},
+			`{"a": {"a": 1}, "b": 2}`,
+			`{"x":{"y":1},"a":2}`,
+		},
+		{map[string]string{"a": "b", "b": "a"}, nil, `{"a": 1, "b": 2}`, `{"b":1,"a":2}`},
+		{
+			map[string]string{"userId": "a", `user"Id`: "b"}, nil,
+			`{"\u0075serId": 1, "user\"Id": 2}`,
+			`{"a":1,"b":2}`,
+		},
+		{map[string]string{"a": "q\"<\n"}, nil, `{"a": 1}`, `{"q\"<\n":1}`},
+		{nil, map[string]string{`a\.b`: "c"}, `{"a.b": 1}`, `{"c":1}`},
+	} {
+		r, err := CompileRenames(test.keys, test.paths)
+		if err != nil {
+			t.Fatalf("%q %q: unexpected err %v", test.keys, test.paths, err)
+		}
+		got, ok := AppendCompactRenameString(nil, test.in, r)
+		if ok != (test.exp != "") || string(got) != test.exp {
+			t.Errorf("«%s» (keys %q, paths %q): got «%s» (ok? %v) != exp «%s»", test.in, test.keys, test.paths, got, ok, test.exp)
+		}
+		gotb, okb := AppendCompactRename([]byte("x"), []byte(test.in), r)
+		if okb != ok || ok && string(gotb) != "x"+string(got) {
+			t.Errorf("«%s»: got «%s» (ok? %v) as bytes != «%s» (ok? %v)", test.in, gotb, okb, got, ok)
+		}
+	}
+
+	r, _ := CompileRenames(map[string]string{"userId": "user_id"}, nil)
+	in := []byte(`{"userId": 1, "a": {"userId": 2, "b": 3}}`)
+	dst := make([]byte, 0, 2*len(in))
+	if allocs := testing.AllocsPerRun(100, func() {
+		AppendCompactRename(dst[:0], in, r)
+	}); allocs != 0 {
+		t.Errorf("got %v allocs != exp 0", allocs)
+	}
+}
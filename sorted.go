@@ -0,0 +1,198 @@
+package chkjson
+
+import (
+	"sort"
+	"unsafe"
+)
+
+// Scratch is reusable scratch space for functions that need more memory than
+// dst to do their work. Reusing a Scratch across calls avoids allocating once
+// it has grown large enough for the input.
+//
+// The zero value is ready to use. A Scratch must not be used concurrently.
+type Scratch struct {
+	buf   []byte
+	spans []keySpan
+
+	// For sorting spans without allocating.
+	sortIn    string
+	sortSpans []keySpan
+}
+
+// keySpan is the span of one object member in output. out[start:colon] is
+// the quoted key.
+type keySpan struct {
+	start, colon, end int
+}
+
+// AppendCompactSorted is exactly like AppendCompact, but also sorts the
+// members of every object by key. Keys are compared byte by byte once
+// unescaped, but are otherwise left as is, as are values. Members with
+// duplicate keys keep their relative order.
+//
+// Sorting uses s for scratch space. If s is nil, this allocates its own.
+//
+// As with AppendCompact, it is valid to pass (src[:0], src) to this function
+// to compact and sort in place.
+func AppendCompactSorted(dst, src []byte, s *Scratch) ([]byte, bool) {
+	return AppendCompactSortedString(dst, *(*string)(unsafe.Pointer(&src)), s)
+}
+
+// AppendCompactSortedString is exactly like AppendCompactSorted, but for
+// strings.
+func AppendCompactSortedString(dst []byte, src string, s *Scratch) ([]byte, bool) {
+	if s == nil {
+		s = new(Scratch)
+	}
+	p := keySorter{in: src, dst: dst, s: s}
+	at, ok := p.value(0)
+	s.spans = s.spans[:0]
+	if !ok || skipSpace(src, at) != len(src) {
+		return nil, false
+	}
+	return p.dst, true
+}
+
+// keySorter compacts like packAny, but sorts object members after compacting
+// them.
+type keySorter struct {
+	in  string
+	dst []byte
+	s   *Scratch
+}
+
+func (p *keySorter) value(at int) (int, bool) {
+	in := p.in
+	if at = skipSpace(in, at); at == len(in) {
+		return at, false
+	}
+	var ok bool
+	switch in[at] {
+	case '{':
+		return p.obj(at)
+	case '[':
+		return p.arr(at)
+	default:
+		p.dst, at, ok = packAny(p.dst, in, at)
+		return at, ok
+	}
+}
+
+func (p *keySorter) obj(at int) (int, bool) {
+	in := p.in
+	p.dst = append(p.dst, '{')
+	if at = skipSpace(in, at+1); at < len(in) && in[at] == '}' {
+		p.dst = append(p.dst, '}')
+		return at + 1, true
+	}
+
+	base := len(p.s.spans)
+	var ok bool
+	for {
+		if at == len(in) || in[at] != '"' {
+			return at, false
+		}
+		span := keySpan{start: len(p.dst)}
+		if p.dst, at, ok = packAny(p.dst, in, at); !ok {
+			return at, false
+		}
+		if at = skipSpace(in, at); at == len(in) || in[at] != ':' {
+			return at, false
+		}
+		span.colon = len(p.dst)
+		p.dst = append(p.dst, ':')
+		if at, ok = p.value(at + 1); !ok {
+			return at, false
+		}
+		span.end = len(p.dst)
+		p.s.spans = append(p.s.spans, span)
+
+		if at = skipSpace(in, at); at == len(in) {
+			return at, false
+		}
+		switch in[at] {
+		case ',':
+			p.dst = append(p.dst, ',')
+			at = skipSpace(in, at+1)
+		case '}':
+			p.dst = p.s.sortMembers(p.dst, p.s.spans[base:])
+			p.s.spans = p.s.spans[:base]
+			p.dst = append(p.dst, '}')
+			return at + 1, true
+		default:
+			return at, false
+		}
+	}
+}
+
+func (p *keySorter) arr(at int) (int, bool) {
+	in := p.in
+	p.dst = append(p.dst, '[')
+	if at = skipSpace(in, at+1); at < len(in) && in[at] == ']' {
+		p.dst = append(p.dst, ']')
+		return at + 1, true
+	}
+
+	var ok bool
+	for {
+		if at, ok = p.value(at); !ok {
+			return at, false
+		}
+		if at = skipSpace(in, at); at == len(in) {
+			return at, false
+		}
+		switch in[at] {
+		case ',':
+			p.dst = append(p.dst, ',')
+			at++
+		case ']':
+			p.dst = append(p.dst, ']')
+			return at + 1, true
+		default:
+			return at, false
+		}
+	}
+}
+
+// sortMembers sorts the comma separated object members at the end of out,
+// returning the updated out.
+func (s *Scratch) sortMembers(out []byte, members []keySpan) []byte {
+	s.sortIn, s.sortSpans = unsafeString(out), members
+	sorted := sort.IsSorted((*spanSorter)(s))
+	if !sorted {
+		sort.Stable((*spanSorter)(s))
+	}
+	s.sortIn, s.sortSpans = "", nil
+	if sorted {
+		return out
+	}
+
+	// We copy the members out and write them back in order.
+	start := len(out)
+	for _, m := range members {
+		if m.start < start {
+			start = m.start
+		}
+	}
+	s.buf = append(s.buf[:0], out[start:]...)
+	out = out[:start]
+	for i, m := range members {
+		if i > 0 {
+			out = append(out, ',')
+		}
+		out = append(out, s.buf[m.start-start:m.end-start]...)
+	}
+	return out
+}
+
+// spanSorter sorts a Scratch's sortSpans by key.
+type spanSorter Scratch
+
+func (s *spanSorter) Len() int { return len(s.sortSpans) }
+func (s *spanSorter) Swap(i, j int) {
+	s.sortSpans[i], s.sortSpans[j] = s.sortSpans[j], s.sortSpans[i]
+}
+func (s *spanSorter) Less(i, j int) bool {
+	mi, mj := s.sortSpans[i], s.sortSpans[j]
+	return unescapedCompare(s.sortIn[mi.start+1:mi.colon-1], s.sortIn[mj.start+1:mj.colon-1]) < 0
+}
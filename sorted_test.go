@@ -0,0 +1,97 @@
+package chkjson
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"testing"
+)
+
+func TestAppendCompactSorted(t *testing.T) {
+	var s Scratch
+	for _, test := range []struct {
+		in  string
+		exp string // empty if invalid
+	}{
+		{`{}`, `{}`},
+		{` { "b" : 1 , "a" : [ 3 , 1.0e1 ] } `, `{"a":[3,1.0e1],"b":1}`},
+		{`{"b":{"d":1,"c":{"f":2,"e":3}},"a":[{"z":1,"y":2},{}]}`, `{"a":[{"y":2,"z":1},{}],"b":{"c":{"e":3,"f":2},"d":1}}`},
+		{`{"b":1,"\u0061":2,"c":3}`, `{"\u0061":2,"b":1,"c":3}`},
+		{`{"a":1,"b":2,"a":3,"\u0061":4}`, `{"a":1,"a":3,"\u0061":4,"b":2}`},
+		{`{"ab":1,"a":2,"":3}`, `{"":3,"a":2,"ab":1}`},
+		{`{"\n":1,"\t":2}`, `{"\t":2,"\n":1}`},
+		{`["z", {"b": null, "a": true}]`, `["z",{"a":true,"b":null}]`},
+		{`1`, `1`},
+
+		{`{"b":1,"a":2`, ``},
+		{`{"b":1,"a":2}}`, ``},
+		{`{"b":1,"a"}`, ``},
+		{`[{"b":1,"a":2},]`, ``},
+	} {
+		got, ok := AppendCompactSortedString(nil, test.in, &s)
+		if ok != (test.exp != "") || string(got) != test.exp {
+			t.Errorf("«%s»: got «%s» (ok? %v) != exp «%s»", test.in, got, ok, test.exp)
+		}
+		in := []byte(test.in)
+		inplace, ok := AppendCompactSorted(in[:0], in, nil)
+		if ok != (test.exp != "") || string(inplace) != test.exp {
+			t.Errorf("«%s»: got inplace «%s» (ok? %v) != exp «%s»", test.in, inplace, ok, test.exp)
+		}
+	}
+
+	// encoding/json sorts map keys when marshaling, meaning our generated
+	// JSON is already sorted. We indent and reverse its keys.
+	for i := 0; i < 3; i++ {
+		big := genBig()
+		var v interface{}
+		if err := json.Unmarshal(big, &v); err != nil {
+			t.Fatal(err)
+		}
+		rev := new(bytes.Buffer)
+		writeReversed(rev, v)
+		if got, ok := AppendCompactSorted(nil, rev.Bytes(), &s); !ok || !bytes.Equal(got, big) {
+			t.Errorf("reversed big: got ok? %v, equal? %v", ok, bytes.Equal(got, big))
+		}
+	}
+
+	in := []byte(`{"c":[{"z":1,"y":2}],"b":{"d":1,"c":2},"a":3}`)
+	buf := make([]byte, 0, len(in))
+	if allocs := testing.AllocsPerRun(100, func() { AppendCompactSorted(buf[:0], in, &s) }); allocs != 0 {
+		t.Errorf("got %v allocs != exp 0", allocs)
+	}
+}
+
+// writeReversed writes v as indented JSON with map keys in reverse order.
+func writeReversed(w *bytes.Buffer, v interface{}) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		w.WriteString("{\n")
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Sort(sort.Reverse(sort.StringSlice(keys)))
+		for i, k := range keys {
+			if i > 0 {
+				w.WriteString(",\n")
+			}
+			kb, _ := json.Marshal(k)
+			w.Write(kb)
+			w.WriteString(" : ")
+			writeReversed(w, v[k])
+		}
+		w.WriteString("\n}")
+	case []interface{}:
+		w.WriteString("[ ")
+		for i, e := range v {
+			if i > 0 {
+				w.WriteString(", ")
+			}
+			writeReversed(w, e)
+		}
+		w.WriteString(" ]")
+	default:
+		b, _ := json.Marshal(v)
+		w.Write(b)
+	}
+}
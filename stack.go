@@ -0,0 +1,259 @@
+package chkjson
+
+import (
+	"unsafe"
+)
+
+// The functions in this file are alternatives to the recursive validating and
+// compacting functions that instead track nesting on an explicit stack of
+// bits. Recursion is faster for typical input, but every level of nesting
+// costs a stack frame; deeply nested input can grow a goroutine's stack
+// significantly. An explicit stack costs one bit per level of nesting.
+
+// ValidStack is exactly like Valid, but uses an explicit stack rather than
+// recursion. This is slower than Valid for typical input, but uses
+// significantly less memory for deeply nested input.
+func ValidStack(b []byte) bool {
+	return ValidStringStack(*(*string)(unsafe.Pointer(&b)))
+}
+
+// ValidStringStack is exactly like ValidString, but uses an explicit stack
+// rather than recursion.
+func ValidStringStack(s string) bool {
+	_, ok := stackAll(nil, s, false)
+	return ok
+}
+
+// AppendCompactStack is exactly like AppendCompact, but uses an explicit
+// stack rather than recursion.
+func AppendCompactStack(dst, src []byte) ([]byte, bool) {
+	return AppendCompactStringStack(dst, *(*string)(unsafe.Pointer(&src)))
+}
+
+// AppendCompactStringStack is exactly like AppendCompactString, but uses an
+// explicit stack rather than recursion.
+func AppendCompactStringStack(dst []byte, src string) ([]byte, bool) {
+	return stackAll(dst, src, true)
+}
+
+// CompactStack is exactly like Compact, but uses an explicit stack rather
+// than recursion.
+func CompactStack(b []byte) ([]byte, bool) {
+	// Compacting never writes past what has been read, meaning we can
+	// compact from b into b.
+	return AppendCompactStack(b[:0], b)
+}
+
+// stackAll validates in, which must be a single JSON value optionally
+// surrounded by whitespace, appending the compact form of in to dst if pack
+// is true.
+func stackAll(dst []byte, in string, pack bool) ([]byte, bool) {
+	var stack smallBitStack
+
+	at := 0
+	var ok bool
+
+value:
+	at = skipSpace(in, at)
+	if at == len(in) {
+		return nil, false
+	}
+	switch in[at] {
+	case '{':
+		at = skipSpace(in, at+1)
+		if at < len(in) && in[at] == '}' {
+			at++
+			if pack {
+				dst = append(dst, '{', '}')
+			}
+			goto after
+		}
+		if pack {
+			dst = append(dst, '{')
+		}
+		stack.push(true)
+		goto key
+	case '[':
+		at = skipSpace(in, at+1)
+		if at < len(in) && in[at] == ']' {
+			at++
+			if pack {
+				dst = append(dst, '[', ']')
+			}
+			goto after
+		}
+		if pack {
+			dst = append(dst, '[')
+		}
+		stack.push(false)
+		goto value
+	case '"':
+		start := at
+		if at, ok = stackStr(in, at); !ok {
+			return nil, false
+		}
+		if pack {
+			dst = append(dst, in[start:at]...)
+		}
+	case 't':
+		if at, ok = stackLit(in, at, "true"); !ok {
+			return nil, false
+		}
+		if pack {
+			dst = append(dst, "true"...)
+		}
+	case 'f':
+		if at, ok = stackLit(in, at, "false"); !ok {
+			return nil, false
+		}
+		if pack {
+			dst = append(dst, "false"...)
+		}
+	case 'n':
+		if at, ok = stackLit(in, at, "null"); !ok {
+			return nil, false
+		}
+		if pack {
+			dst = append(dst, "null"...)
+		}
+	case '-', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
+		start := at
+		var r reason
+		if at, r = errNum(in, at); r != rOK {
+			return nil, false
+		}
+		if pack {
+			dst = append(dst, in[start:at]...)
+		}
+	default:
+		return nil, false
+	}
+
+after:
+	at = skipSpace(in, at)
+	if stack.n == 0 {
+		if at != len(in) {
+			return nil, false
+		}
+		return dst, true
+	}
+	if at == len(in) {
+		return nil, false
+	}
+	switch c := in[at]; {
+	case c == ',':
+		at++
+		if pack {
+			dst = append(dst, ',')
+		}
+		if stack.top() {
+			goto key
+		}
+		goto value
+	case c == '}' && stack.top(), c == ']' && !stack.top():
+		at++
+		if pack {
+			dst = append(dst, c)
+		}
+		stack.pop()
+		goto after
+	default:
+		return nil, false
+	}
+
+key:
+	at = skipSpace(in, at)
+	if at == len(in) || in[at] != '"' {
+		return nil, false
+	}
+	{
+		start := at
+		if at, ok = stackStr(in, at); !ok {
+			return nil, false
+		}
+		if pack {
+			dst = append(dst, in[start:at]...)
+		}
+	}
+	at = skipSpace(in, at)
+	if at == len(in) || in[at] != ':' {
+		return nil, false
+	}
+	at++
+	if pack {
+		dst = append(dst, ':')
+	}
+	goto value
+}
+
+// smallBitStack is a bitStack that avoids allocating for the first 256 levels
+// of nesting, which is deeper than most input ever goes.
+type smallBitStack struct {
+	small [4]uint64
+	more  bitStack
+	n     int
+}
+
+func (s *smallBitStack) push(b bool) {
+	if s.n >= 64*len(s.small) {
+		s.more.push(b)
+	} else {
+		mask := uint64(1) << uint(s.n%64)
+		if b {
+			s.small[s.n/64] |= mask
+		} else {
+			s.small[s.n/64] &^= mask
+		}
+	}
+	s.n++
+}
+
+func (s *smallBitStack) pop() {
+	s.n--
+	if s.n >= 64*len(s.small) {
+		s.more.pop()
+	}
+}
+
+func (s *smallBitStack) top() bool {
+	n := s.n - 1
+	if n >= 64*len(s.small) {
+		return s.more.top()
+	}
+	return s.small[n/64]&(1<<uint(n%64)) != 0
+}
+
+func skipSpace(in string, at int) int {
+	for ; at < len(in); at++ {
+		switch in[at] {
+		case ' ', '\r', '\t', '\n':
+		default:
+			return at
+		}
+	}
+	return at
+}
+
+// stackStr returns the offset after the string beginning at in[at].
+func stackStr(in string, at int) (int, bool) {
+	for at++; at < len(in); at++ {
+		switch c := in[at]; {
+		case c < 0x20:
+			return at, false
+		case c == '"':
+			return at + 1, true
+		case c == '\\':
+			var r reason
+			if at, r = errEscape(in, at+1); r != rOK {
+				return at, false
+			}
+			at-- // at is past the escape; undo the loop increment
+		}
+	}
+	return at, false
+}
+
+func stackLit(in string, at int, lit string) (int, bool) {
+	end := at + len(lit)
+	return end, end <= len(in) && in[at:end] == lit
+}
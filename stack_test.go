@@ -0,0 +1,77 @@
+package chkjson
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestStack(t *testing.T) {
+	tests := append([]string(nil), edgeCases...)
+	tests = append(tests,
+		`{"a" : [ 1 , { } , [ ] , "\u00e9" ] , "b":{"c":null}}`,
+		`[1,]`,
+		`{"a":1,}`,
+		`{"a":1]`,
+		`[1}`,
+		`{"a"}`,
+		`{1:2}`,
+		"[\"\\uzzzz\"]",
+		"[\"a\nb\"]",
+	)
+	for i := 0; i < 3; i++ {
+		big := genBig()
+		indented := new(bytes.Buffer)
+		json.Indent(indented, big, " ", "\t ")
+		tests = append(tests, string(big), indented.String())
+	}
+
+	for _, test := range tests {
+		in := []byte(test)
+		exp := Valid(in)
+		if got := ValidStack(in); got != exp {
+			t.Errorf("«%s»: got valid? %v != exp %v", test, got, exp)
+		}
+		if got := ValidStringStack(test); got != exp {
+			t.Errorf("«%s»: got valid as string? %v != exp %v", test, got, exp)
+		}
+
+		expPact, _ := AppendCompact(nil, in)
+		pact, ok := AppendCompactStack(nil, in)
+		if ok != exp || !bytes.Equal(pact, expPact) {
+			t.Errorf("«%s»: got compact «%s» (ok? %v) != exp «%s» (ok? %v)", test, pact, ok, expPact, exp)
+		}
+		pact, ok = AppendCompactStringStack(nil, test)
+		if ok != exp || !bytes.Equal(pact, expPact) {
+			t.Errorf("«%s»: got compact string «%s» (ok? %v) != exp «%s» (ok? %v)", test, pact, ok, expPact, exp)
+		}
+		pact, ok = CompactStack(append([]byte(nil), in...))
+		if ok != exp || !bytes.Equal(pact, expPact) {
+			t.Errorf("«%s»: got compact inplace «%s» (ok? %v) != exp «%s» (ok? %v)", test, pact, ok, expPact, exp)
+		}
+	}
+}
+
+func TestStackDeep(t *testing.T) {
+	n := 1000000
+	if testing.Short() {
+		n = 1000
+	}
+	deep := strings.Repeat(`[{"a": `, n) + "1" + strings.Repeat("}]", n)
+	if !ValidStringStack(deep) {
+		t.Error("deep input unexpectedly invalid")
+	}
+	pact, ok := CompactStack([]byte(deep))
+	if exp := strings.Replace(deep, " ", "", -1); !ok || string(pact) != exp {
+		t.Errorf("deep compact got ok? %v, equal? %v", ok, string(pact) == exp)
+	}
+	if ValidStringStack(deep[:len(deep)-1]) {
+		t.Error("truncated deep input unexpectedly valid")
+	}
+
+	shallow := []byte(strings.Repeat("[", 200) + strings.Repeat("]", 200))
+	if allocs := testing.AllocsPerRun(100, func() { ValidStack(shallow) }); allocs != 0 {
+		t.Errorf("got %v allocs != exp 0", allocs)
+	}
+}
@@ -0,0 +1,579 @@
+package chkjson
+
+import (
+	"errors"
+	"io"
+)
+
+// This file contains a resumable, byte at a time JSON scanner. Unlike any,
+// which recurses and needs all input up front, the scanner keeps its state
+// (and an explicit stack of the objects and arrays it is within) between
+// writes, meaning it can validate input as it arrives.
+//
+// The scanner fails with the same offsets and reasons as the parser.
+
+// scanner states.
+const (
+	sValue         uint8 = iota // expecting a value
+	sValueOrArrEnd              // after '['
+	sKeyOrObjEnd                // after '{'
+	sKey                        // after ',' in an object
+	sColon                      // after an object key
+	sAfter                      // after a value
+	sStr                        // in a string
+	sStrEsc                     // after '\' in a string
+	sStrU                       // in the hex digits of a \u escape
+	sLit                        // in true, false, or null
+	sNeg                        // after a leading '-'
+	sZero                       // after a leading '0'
+	sInt                        // in integer digits
+	sDot                        // after '.'
+	sFrac                       // in fraction digits
+	sE                          // after 'e' or 'E'
+	sESign                      // after the exponent's sign
+	sExp                        // in exponent digits
+)
+
+// snippetLen is how many bytes the scanner keeps for error snippets.
+const snippetLen = 16
+
+type scanner struct {
+	state uint8
+	key   bool   // if in a string, whether it is an object key
+	hex   uint8  // if in a \u escape, how many hex digits remain
+	lit   string // if in a literal, the remaining bytes of it
+	litR  reason // if in a literal, the reason for failing it
+	stack bitStack
+
+	off       int // offset of the first byte of the next write
+	lines     int
+	lineStart int
+	tail      [snippetLen]byte // the last bytes written, for error snippets
+	ntail     int
+
+	err *Error
+
+	// If compact is true, write compacts what it is given in place by
+	// dropping whitespace between tokens. After a successful write, the
+	// first w bytes are the compacted input. seg is the start of the input
+	// not yet moved into place.
+	compact bool
+	w, seg  int
+}
+
+// bitStack is a stack of bools, which for the scanner are whether we are in
+// an object (true) or array (false).
+type bitStack struct {
+	bits []uint64
+	n    int
+}
+
+func (s *bitStack) push(b bool) {
+	if s.n/64 == len(s.bits) {
+		s.bits = append(s.bits, 0)
+	}
+	mask := uint64(1) << uint(s.n%64)
+	if b {
+		s.bits[s.n/64] |= mask
+	} else {
+		s.bits[s.n/64] &^= mask
+	}
+	s.n++
+}
+
+func (s *bitStack) pop() { s.n-- }
+
+func (s *bitStack) top() bool {
+	n := s.n - 1
+	return s.bits[n/64]&(1<<uint(n%64)) != 0
+}
+
+func (s *scanner) reset() {
+	bits := s.stack.bits[:0]
+	*s = scanner{}
+	s.stack.bits = bits
+}
+
+// write scans b, returning false if the scanner has failed, in which case
+// s.err is set.
+func (s *scanner) write(b []byte) bool {
+	if s.err != nil {
+		return false
+	}
+	s.w, s.seg = 0, 0
+	for i := 0; i < len(b); i++ {
+		c := b[i]
+	redo:
+		switch s.state {
+		case sValue, sValueOrArrEnd:
+			switch c {
+			case ' ', '\t', '\r', '\n':
+				s.space(b, i)
+			case '{':
+				s.stack.push(true)
+				s.state = sKeyOrObjEnd
+			case '[':
+				s.stack.push(false)
+				s.state = sValueOrArrEnd
+			case '"':
+				s.state, s.key = sStr, false
+			case 't':
+				s.state, s.lit, s.litR = sLit, "rue", rTrue
+			case 'f':
+				s.state, s.lit, s.litR = sLit, "alse", rFalse
+			case 'n':
+				s.state, s.lit, s.litR = sLit, "ull", rNull
+			case '-':
+				s.state = sNeg
+			case '0':
+				s.state = sZero
+			case '1', '2', '3', '4', '5', '6', '7', '8', '9':
+				s.state = sInt
+			case ']':
+				if s.state == sValueOrArrEnd {
+					s.stack.pop()
+					s.state = sAfter
+					break
+				}
+				return s.fail(b, i, rValue)
+			default:
+				if s.state == sValueOrArrEnd {
+					return s.fail(b, i, rValueOrArrEnd)
+				}
+				return s.fail(b, i, rValue)
+			}
+
+		case sKeyOrObjEnd, sKey:
+			switch c {
+			case ' ', '\t', '\r', '\n':
+				s.space(b, i)
+			case '"':
+				s.state, s.key = sStr, true
+			case '}':
+				if s.state == sKeyOrObjEnd {
+					s.stack.pop()
+					s.state = sAfter
+					break
+				}
+				return s.fail(b, i, rKey)
+			default:
+				if s.state == sKeyOrObjEnd {
+					return s.fail(b, i, rKeyOrObjEnd)
+				}
+				return s.fail(b, i, rKey)
+			}
+
+		case sColon:
+			switch c {
+			case ' ', '\t', '\r', '\n':
+				s.space(b, i)
+			case ':':
+				s.state = sValue
+			default:
+				return s.fail(b, i, rColon)
+			}
+
+		case sAfter:
+			switch c {
+			case ' ', '\t', '\r', '\n':
+				s.space(b, i)
+			case ',':
+				if s.stack.n == 0 {
+					return s.fail(b, i, rEnd)
+				}
+				if s.stack.top() {
+					s.state = sKey
+				} else {
+					s.state = sValue
+				}
+			case '}', ']':
+				if s.stack.n == 0 || s.stack.top() != (c == '}') {
+					return s.fail(b, i, s.afterReason())
+				}
+				s.stack.pop()
+			default:
+				return s.fail(b, i, s.afterReason())
+			}
+
+		case sStr:
+			// Strings are usually long; we skip along quickly.
+			for c >= 0x20 && c != '"' && c != '\\' {
+				if i++; i == len(b) {
+					goto done
+				}
+				c = b[i]
+			}
+			switch c {
+			case '"':
+				if s.key {
+					s.state = sColon
+				} else {
+					s.state = sAfter
+				}
+			case '\\':
+				s.state = sStrEsc
+			default:
+				return s.fail(b, i, rStrChar)
+			}
+
+		case sStrEsc:
+			switch c {
+			case 'b', 'f', 'n', 'r', 't', '\\', '/', '"':
+				s.state = sStr
+			case 'u':
+				s.state, s.hex = sStrU, 4
+			default:
+				return s.fail(b, i, rEscape)
+			}
+
+		case sStrU:
+			if !isHex(c) {
+				return s.fail(b, i, rHex)
+			}
+			if s.hex--; s.hex == 0 {
+				s.state = sStr
+			}
+
+		case sLit:
+			if c != s.lit[0] {
+				return s.fail(b, i, s.litR)
+			}
+			if s.lit = s.lit[1:]; len(s.lit) == 0 {
+				s.state = sAfter
+			}
+
+		case sNeg:
+			switch {
+			case c == '0':
+				s.state = sZero
+			case isNat(c):
+				s.state = sInt
+			default:
+				return s.fail(b, i, rNegDigit)
+			}
+
+		case sZero, sInt:
+			switch {
+			case isNum(c) && s.state == sInt:
+			case c == '.':
+				s.state = sDot
+			case isE(c):
+				s.state = sE
+			default:
+				s.state = sAfter
+				goto redo
+			}
+
+		case sDot:
+			if !isNum(c) {
+				return s.fail(b, i, rDotDigit)
+			}
+			s.state = sFrac
+
+		case sFrac:
+			switch {
+			case isNum(c):
+			case isE(c):
+				s.state = sE
+			default:
+				s.state = sAfter
+				goto redo
+			}
+
+		case sE:
+			switch {
+			case c == '+' || c == '-':
+				s.state = sESign
+			case isNum(c):
+				s.state = sExp
+			default:
+				return s.fail(b, i, rExpDigit)
+			}
+
+		case sESign:
+			if !isNum(c) {
+				return s.fail(b, i, rExpDigit)
+			}
+			s.state = sExp
+
+		case sExp:
+			if !isNum(c) {
+				s.state = sAfter
+				goto redo
+			}
+		}
+	}
+
+done:
+	if s.compact {
+		s.w += copy(b[s.w:], b[s.seg:])
+		s.seg = 0
+	}
+	s.off += len(b)
+	s.keepTail(b)
+	return true
+}
+
+// space handles the whitespace at b[i] between tokens, tracking lines and, if
+// compacting, dropping it.
+func (s *scanner) space(b []byte, i int) {
+	if b[i] == '\n' {
+		s.lines++
+		s.lineStart = s.off + i + 1
+	}
+	if s.compact {
+		s.w += copy(b[s.w:], b[s.seg:i])
+		s.seg = i + 1
+	}
+}
+
+// afterReason returns why we failed after a value.
+func (s *scanner) afterReason() reason {
+	if s.stack.n == 0 {
+		return rEnd
+	}
+	if s.stack.top() {
+		return rObjNext
+	}
+	return rArrNext
+}
+
+// finish returns nil if everything written was exactly one JSON value, and
+// otherwise the *Error for why it was not.
+func (s *scanner) finish() *Error {
+	if s.err != nil {
+		return s.err
+	}
+
+	var r reason
+	switch s.state {
+	case sValue:
+		r = rValue
+	case sValueOrArrEnd:
+		r = rValueOrArrEnd
+	case sKeyOrObjEnd:
+		r = rKeyOrObjEnd
+	case sKey:
+		r = rKey
+	case sColon:
+		r = rColon
+	case sStr:
+		r = rStrEnd
+	case sStrEsc:
+		r = rEscape
+	case sStrU:
+		r = rHex
+	case sLit:
+		r = s.litR
+	case sNeg:
+		r = rNegDigit
+	case sDot:
+		r = rDotDigit
+	case sE, sESign:
+		r = rExpDigit
+	default: // after a value, including numbers that can end here
+		if s.stack.n == 0 {
+			return nil
+		}
+		r = s.afterReason()
+	}
+	s.fail(nil, 0, r)
+	return s.err
+}
+
+// fail sets s.err for failing at b[i], or at the end of input if b is empty,
+// and returns false.
+func (s *scanner) fail(b []byte, i int, r reason) bool {
+	at := s.off + i
+	found := "end of input"
+	if i < len(b) {
+		found = describeByte(b[i])
+	}
+
+	// If compacting, only input from seg on is not yet overwritten, and
+	// the tail is of compacted input.
+	from := 0
+	if s.compact {
+		from = s.seg
+	}
+	before := i - snippetLen
+	if before < from {
+		before = from
+	}
+	after := i + snippetLen
+	if after > len(b) {
+		after = len(b)
+	}
+	snippet := make([]byte, 0, 2*snippetLen)
+	if need := snippetLen - (i - before); need > 0 && !s.compact {
+		if need > s.ntail {
+			need = s.ntail
+		}
+		snippet = append(snippet, s.tail[s.ntail-need:s.ntail]...)
+	}
+	snippet = append(snippet, b[before:after]...)
+
+	s.err = &Error{
+		Kind:    r.kind(),
+		Offset:  at,
+		Line:    s.lines + 1,
+		Column:  at - s.lineStart + 1,
+		Reason:  r.String(),
+		Found:   found,
+		Snippet: string(snippet),
+	}
+	return false
+}
+
+// keepTail keeps the last snippetLen bytes seen across writes.
+func (s *scanner) keepTail(b []byte) {
+	if len(b) >= snippetLen {
+		s.ntail = copy(s.tail[:], b[len(b)-snippetLen:])
+		return
+	}
+	if keep := snippetLen - len(b); s.ntail > keep {
+		copy(s.tail[:], s.tail[s.ntail-keep:s.ntail])
+		s.ntail = keep
+	}
+	s.ntail += copy(s.tail[s.ntail:], b)
+}
+
+// Validator validates a stream of JSON that is written to it in arbitrary
+// chunks, such as a request body that arrives piece by piece. After writing
+// everything, Close reports whether the whole stream was exactly one JSON
+// value, optionally surrounded by whitespace.
+//
+// A Validator gives the same results as Valid, and its errors have the same
+// offset, line, column, and reason that ValidErr would return if given the
+// whole stream at once. Because a Validator does not keep what was written,
+// an error's snippet may include less input than ValidErr's.
+//
+// A Validator uses an explicit stack for nesting rather than recursing,
+// meaning memory use is bounded by how deeply the JSON nests, not its size.
+//
+// The zero value is ready to use.
+type Validator struct {
+	s      scanner
+	closed bool
+}
+
+var errClosed = errors.New("chkjson: write to closed Validator")
+
+// Write implements io.Writer, validating p as the next chunk of the stream.
+//
+// If the stream is known to be invalid after p, this returns an *Error and
+// how many bytes of p were valid. Once a Write fails, every following Write
+// returns the same error.
+func (v *Validator) Write(p []byte) (int, error) {
+	if v.closed {
+		return 0, errClosed
+	}
+	off := v.s.off
+	if !v.s.write(p) {
+		n := v.s.err.Offset - off
+		if n < 0 {
+			n = 0
+		}
+		return n, v.s.err
+	}
+	return len(p), nil
+}
+
+// Close returns nil if everything written was exactly one JSON value, and
+// otherwise an *Error describing why it was not. Closing does not release
+// anything; it only ends the stream. Use Reset to reuse the Validator.
+func (v *Validator) Close() error {
+	v.closed = true
+	if err := v.s.finish(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Reset resets the Validator to validate a new stream, keeping memory that
+// was allocated to track nesting.
+func (v *Validator) Reset() {
+	v.s.reset()
+	v.closed = false
+}
+
+// streamBufSize is the size of the buffer used for reading.
+const streamBufSize = 32 << 10
+
+// ValidReader returns nil if everything read from r until io.EOF is exactly
+// one JSON value, optionally surrounded by whitespace.
+//
+// If the JSON is invalid, this returns an *Error with the same offset, line,
+// column, and reason that ValidErr would return if given all input at once.
+// Because only a bounded amount of input is kept in memory, the error's
+// snippet may include less input after the failing offset than ValidErr's.
+// Reading stops as soon as the input is known to be invalid.
+//
+// If reading fails with an error other than io.EOF, that error is returned.
+//
+// This uses a fixed size buffer and an explicit stack for nesting, meaning
+// memory use is bounded by how deeply the JSON nests, not its size.
+func ValidReader(r io.Reader) error {
+	var s scanner
+	buf := make([]byte, streamBufSize)
+	for {
+		n, err := r.Read(buf)
+		if !s.write(buf[:n]) {
+			return s.err
+		}
+		if err == io.EOF {
+			if err := s.finish(); err != nil {
+				return err
+			}
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// CompactStream compacts the JSON read from r until io.EOF to w, returning the
+// number of bytes written to w. Compacted output is written as it becomes
+// available, one read at a time, with any whitespace between tokens dropped.
+//
+// Output that has been written cannot be taken back: if the input turns out to
+// be invalid, everything before the failing read has already been written,
+// and this returns the count of bytes written along with an *Error. The
+// error's offset, line, column, and reason are what ValidErr would return if
+// given all input at once, but its snippet only includes input from the
+// failing read. Callers that must not forward invalid JSON should write to a
+// destination that can be discarded, or validate first.
+//
+// If reading or writing fails, that error is returned instead.
+//
+// As with ValidReader, this uses a fixed size buffer and an explicit stack for
+// nesting, meaning memory use is bounded by how deeply the JSON nests, not its
+// size.
+func CompactStream(w io.Writer, r io.Reader) (int64, error) {
+	s := scanner{compact: true}
+	buf := make([]byte, streamBufSize)
+	var written int64
+	for {
+		n, err := r.Read(buf)
+		if !s.write(buf[:n]) {
+			return written, s.err
+		}
+		if s.w > 0 {
+			wn, werr := w.Write(buf[:s.w])
+			written += int64(wn)
+			if werr != nil {
+				return written, werr
+			}
+		}
+		if err == io.EOF {
+			if err := s.finish(); err != nil {
+				return written, err
+			}
+			return written, nil
+		}
+		if err != nil {
+			return written, err
+		}
+	}
+}
@@ -0,0 +1,301 @@
+package chkjson
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"math/rand"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+// edgeCases covers most edge cases of validating, for checking that other
+// validators agree with Valid and ValidStringErr.
+var edgeCases = []string{
+	"",
+	"   ",
+	" z",
+	" 1  1",
+	" 1  {}",
+	" 1  []",
+	" 1  \"n\"",
+	"00",
+	"1 ,",
+	"\n\n  [\n1,\n\t{\"a\": tru",
+
+	// string
+	`"foo"`,
+	"\"\xe2",
+	"\"\xe2\x80\xa8\xe2\x80\xa9\"",
+	` "\uaaaa\uaaaa" `,
+	` "<a href=\"x\">&amp;</a>" `,
+	` "\`,
+	` "\z`,
+	` "\uazaa" `,
+	`"\uaa`,
+	" \"f\x00o\"",
+	` "foo`,
+
+	// number
+	"1",
+	"  0 ",
+	"-0",
+	" -0e+0 ",
+	" -103e+1 ",
+	"-0.01e+006",
+	"-",
+	"-z",
+	"1.",
+	"1z",
+	"1.e3",
+	"1e",
+	"1e+",
+	"1e+z",
+	" 03e+1 ",
+	"-0.01e+0.6",
+
+	// object
+	"{}",
+	` {}    `,
+	`{"foo": [{"":3, "4": "3"}, 4, {}], "t_wo": 1}`,
+	strings.Repeat(`{"f":`, 1000) + "{}" + strings.Repeat("}", 1000),
+	"{",
+	"{1",
+	`{"foo"`,
+	"{\n  \"foo\" 1}",
+	`{"foo":`,
+	`{"foo":1`,
+	`{"foo":1]`,
+	`{"foo":1,`,
+	`{"foo":1,}`,
+	`{"foo": true, f "a": true}`,
+	"{}}",
+
+	// array
+	"[]",
+	"[ ]",
+	`[ 1, {}]`,
+	strings.Repeat("[", 1000) + strings.Repeat("]", 1000),
+	"[",
+	"[}",
+	"[1",
+	"[1a]",
+	"[1,",
+	"[1,]",
+	"[1}",
+	"[[[]]] ]",
+	"[\n1,\r\n\xff]",
+	"[[[tru]]]",
+
+	// literals
+	"true",
+	"   true ",
+	"tru",
+	"trUe",
+	"fals",
+	"falsee",
+	" null ",
+	"nulL",
+	" nulll ",
+}
+
+// chkStreamErr checks that got, from a streaming validator, matches what
+// ValidStringErr returns for in.
+func chkStreamErr(t *testing.T, name, in string, got error) {
+	t.Helper()
+	exp := ValidStringErr(in)
+	if (got == nil) != (exp == nil) {
+		t.Errorf("%s «%s»: got err %v, exp %v", name, in, got, exp)
+		return
+	}
+	if got == nil {
+		return
+	}
+	g, e := got.(*Error), exp.(*Error)
+	if g.Kind != e.Kind || g.Offset != e.Offset || g.Line != e.Line || g.Column != e.Column || g.Reason != e.Reason || g.Found != e.Found {
+		t.Errorf("%s «%s»: got err %v, exp %v", name, in, got, exp)
+	}
+}
+
+func TestValidReader(t *testing.T) {
+	for _, in := range edgeCases {
+		chkStreamErr(t, "whole", in, ValidReader(strings.NewReader(in)))
+		chkStreamErr(t, "one byte", in, ValidReader(iotest.OneByteReader(strings.NewReader(in))))
+		chkStreamErr(t, "data err", in, ValidReader(iotest.DataErrReader(strings.NewReader(in))))
+	}
+
+	for i := 0; i < 5; i++ {
+		b := genBig()
+		if err := ValidReader(iotest.HalfReader(bytes.NewReader(b))); err != nil {
+			t.Errorf("unexpected err %v", err)
+		}
+		b = append(b, "\n\n   x"...)
+		chkStreamErr(t, "big", string(b), ValidReader(iotest.HalfReader(bytes.NewReader(b))))
+	}
+
+	// Deep nesting must not recurse.
+	deep := strings.Repeat("[", 1000000) + strings.Repeat("]", 1000000)
+	if err := ValidReader(strings.NewReader(deep)); err != nil {
+		t.Errorf("unexpected err on deep input: %v", err)
+	}
+
+	// Read errors are returned.
+	rerr := errors.New("read failure")
+	if err := ValidReader(io.MultiReader(strings.NewReader("[1,"), errReader{rerr})); err != rerr {
+		t.Errorf("got err %v != exp %v", err, rerr)
+	}
+}
+
+func TestValidReaderSnippet(t *testing.T) {
+	in := strings.Repeat(" ", 20) + `{"abc" 1}` + strings.Repeat(" ", 20)
+	exp := ValidStringErr(in).(*Error)
+	got := ValidReader(strings.NewReader(in)).(*Error)
+	if got.Snippet != exp.Snippet {
+		t.Errorf("got snippet %q != exp %q", got.Snippet, exp.Snippet)
+	}
+	// Reading byte by byte, we have nothing past the failing byte.
+	got = ValidReader(iotest.OneByteReader(strings.NewReader(in))).(*Error)
+	if exp := exp.Snippet[:17]; got.Snippet != exp {
+		t.Errorf("got snippet %q != exp %q", got.Snippet, exp)
+	}
+}
+
+type errReader struct{ err error }
+
+func (r errReader) Read([]byte) (int, error) { return 0, r.err }
+
+// writeChunks writes in to v in random chunks, stopping at the first error.
+func writeChunks(v *Validator, in []byte) (int, error) {
+	var written int
+	for len(in) > 0 {
+		chunk := rand.Intn(len(in)) + 1
+		n, err := v.Write(in[:chunk])
+		written += n
+		if err != nil {
+			return written, err
+		}
+		in = in[chunk:]
+	}
+	return written, nil
+}
+
+func TestValidator(t *testing.T) {
+	var v Validator
+	for _, in := range edgeCases {
+		for i := 0; i < 5; i++ {
+			v.Reset()
+			n, err := writeChunks(&v, []byte(in))
+			if err != nil {
+				if n != err.(*Error).Offset {
+					t.Errorf("«%s»: got n %d != err offset %d", in, n, err.(*Error).Offset)
+				}
+				if _, again := v.Write([]byte("1")); again != err {
+					t.Errorf("«%s»: write after failure got %v != exp %v", in, again, err)
+				}
+			}
+			closeErr := v.Close()
+			if err != nil && closeErr != err {
+				t.Errorf("«%s»: close after failure got %v != exp %v", in, closeErr, err)
+			}
+			chkStreamErr(t, "validator", in, closeErr)
+			if (closeErr == nil) != ValidString(in) {
+				t.Errorf("«%s»: got valid %v != Valid", in, closeErr == nil)
+			}
+		}
+	}
+
+	for i := 0; i < 5; i++ {
+		v.Reset()
+		b := genBig()
+		if _, err := writeChunks(&v, b); err != nil {
+			t.Errorf("unexpected write err %v", err)
+		}
+		if err := v.Close(); err != nil {
+			t.Errorf("unexpected close err %v", err)
+		}
+	}
+
+	v.Reset()
+	v.Write([]byte("{}"))
+	v.Close()
+	if _, err := v.Write([]byte(" ")); err == nil {
+		t.Error("unexpected successful write after close")
+	}
+}
+
+func TestValidatorCopy(t *testing.T) {
+	in := `{"a": [1, 2, {"b": null}], "c": "d"}`
+	var v Validator
+	if _, err := io.Copy(&v, iotest.OneByteReader(strings.NewReader(in))); err != nil {
+		t.Fatalf("unexpected copy err %v", err)
+	}
+	if err := v.Close(); err != nil {
+		t.Errorf("unexpected close err %v", err)
+	}
+}
+
+type errWriter struct{ err error }
+
+func (w errWriter) Write([]byte) (int, error) { return 0, w.err }
+
+func TestCompactStream(t *testing.T) {
+	ins := append([]string(nil), edgeCases...)
+	for i := 0; i < 3; i++ {
+		b := genBig()
+		indented := new(bytes.Buffer)
+		json.Indent(indented, b, "\n", " \t")
+		ins = append(ins, indented.String(), indented.String()+" x")
+	}
+
+	for _, in := range ins {
+		exp, _ := AppendCompactString(nil, in)
+		for _, r := range []struct {
+			name string
+			r    io.Reader
+		}{
+			{"whole", strings.NewReader(in)},
+			{"one byte", iotest.OneByteReader(strings.NewReader(in))},
+			{"half", iotest.HalfReader(strings.NewReader(in))},
+			{"data err", iotest.DataErrReader(strings.NewReader(in))},
+		} {
+			out := new(bytes.Buffer)
+			n, err := CompactStream(out, r.r)
+			chkStreamErr(t, "compact "+r.name, in, err)
+			if n != int64(out.Len()) {
+				t.Errorf("compact %s «%s»: got n %d != written %d", r.name, in, n, out.Len())
+			}
+			if err == nil && !bytes.Equal(out.Bytes(), exp) {
+				t.Errorf("compact %s «%s»: got «%s» != exp «%s»", r.name, in, out.Bytes(), exp)
+			}
+			if valid := strings.TrimSuffix(in, " x"); err != nil && valid != in {
+				// Only reads before the failing read are written.
+				if exp, _ := AppendCompactString(nil, valid); !bytes.HasPrefix(exp, out.Bytes()) {
+					t.Errorf("compact %s: wrote %d bytes that are not a prefix of the compacted input", r.name, out.Len())
+				}
+			}
+		}
+	}
+
+	deep := strings.Repeat("[ ", 1000000) + strings.Repeat("] ", 1000000)
+	out := new(bytes.Buffer)
+	if n, err := CompactStream(out, strings.NewReader(deep)); err != nil || n != 2000000 {
+		t.Errorf("deep input: got n %d, err %v", n, err)
+	}
+
+	werr := errors.New("write failure")
+	if n, err := CompactStream(errWriter{werr}, strings.NewReader("[1, 2]")); n != 0 || err != werr {
+		t.Errorf("got n %d, err %v != exp 0, %v", n, err, werr)
+	}
+	rerr := errors.New("read failure")
+	if _, err := CompactStream(new(bytes.Buffer), io.MultiReader(strings.NewReader("[1,"), errReader{rerr})); err != rerr {
+		t.Errorf("got err %v != exp %v", err, rerr)
+	}
+
+	// Snippets only include input that was not overwritten.
+	_, err := CompactStream(new(bytes.Buffer), strings.NewReader(`{"a" : 1 , "b" : x}`))
+	if e, ok := err.(*Error); !ok || e.Offset != 17 || e.Snippet != `x}` {
+		t.Errorf("got err %#v", err)
+	}
+}
@@ -0,0 +1,253 @@
+package chkjson
+
+import (
+	"strconv"
+	"unicode/utf8"
+	"unsafe"
+)
+
+// TruncateOptions configures AppendCompactTruncated. Zero fields do not limit
+// anything.
+type TruncateOptions struct {
+	// MaxString, if positive, is the maximum number of bytes of string
+	// values, as written and excluding quotes. Longer strings are cut to at
+	// most MaxString bytes, without splitting escapes or UTF-8 sequences,
+	// and Ellipsis is appended. Object keys are never cut.
+	MaxString int
+	// Ellipsis is appended to cut strings and begins the markers of left
+	// out elements and members. If empty, "…" is used.
+	Ellipsis string
+
+	// MaxElems, if positive, is the maximum number of elements of arrays
+	// and members of objects. Further elements are replaced with one
+	// final "… n more" element, and further members with one final
+	// "…":"n more" member, where … is Ellipsis and n is the number of
+	// elements or members left out. If a kept member's key already is
+	// the marker's key, the marker's key repeats Ellipsis until it is
+	// unique, such that objects never end up with duplicate keys.
+	MaxElems int
+
+	// MaxDepth, if positive, is the maximum nesting depth of objects and
+	// arrays, as in Options.MaxDepth. Objects and arrays that are deeper
+	// keep none of their members or elements, such that for example
+	// {"a":1,"b":2} becomes {"…":"2 more"}.
+	MaxDepth int
+}
+
+const ellipsis = "…"
+
+// AppendCompactTruncated is like AppendCompact, but also truncates long
+// strings, objects, and arrays, and deeply nested values, as configured by
+// opts, which may be nil. This is meant for bounded previews of JSON, such as
+// for logs. The output is always valid JSON: strings are cut on character
+// boundaries, and members and elements that are left out are replaced with a
+// marker noting how many there were.
+//
+// For example, with a MaxString of 3 and a MaxElems of 2,
+// {"a": "abcdef", "b": [1, 2, 3, 4], "c": 5} becomes
+// {"a":"abc…","b":[1,2,"… 2 more"],"…":"1 more"}.
+//
+// Everything that is left out is still validated. This function assumes and
+// returns ownership of dst. If src is invalid, this will return nil. Markers
+// can make the output longer than the input, meaning dst must not share
+// memory with src.
+func AppendCompactTruncated(dst, src []byte, opts *TruncateOptions) ([]byte, bool) {
+	return AppendCompactTruncatedString(dst, *(*string)(unsafe.Pointer(&src)), opts)
+}
+
+// AppendCompactTruncatedString is exactly like AppendCompactTruncated, but for
+// compacting strings.
+func AppendCompactTruncatedString(dst []byte, src string, opts *TruncateOptions) ([]byte, bool) {
+	t := truncator{walker: walker{in: src, dst: dst}}
+	if opts != nil {
+		t.o = *opts
+	}
+	if t.o.Ellipsis == "" {
+		t.o.Ellipsis = ellipsis
+	}
+	at, ok := t.value(0)
+	if !ok || skipSpace(src, at) != len(src) {
+		return nil, false
+	}
+	return t.dst, true
+}
+
+// truncator compacts like packAny, but truncates as configured.
+type truncator struct {
+	walker
+	o       TruncateOptions
+	depth   int
+	scratch []byte // for unescaping keys
+}
+
+func (t *truncator) value(at int) (int, bool) {
+	in := t.in
+	if at = skipSpace(in, at); at == len(in) {
+		return at, false
+	}
+	var ok bool
+	switch in[at] {
+	case '{':
+		return t.obj(at)
+	case '[':
+		return t.arr(at)
+	case '"':
+		return t.str(at)
+	default:
+		t.dst, at, ok = packAny(t.dst, in, at)
+		return at, ok
+	}
+}
+
+// limit returns the number of members or elements to keep of the object or
+// array we just entered, or -1 if all should be kept.
+func (t *truncator) limit() int {
+	switch {
+	case t.o.MaxDepth > 0 && t.depth > t.o.MaxDepth:
+		return 0
+	case t.o.MaxElems > 0:
+		return t.o.MaxElems
+	default:
+		return -1
+	}
+}
+
+// more appends the marker for more left out members or elements. For
+// objects, the marker's key is Ellipsis repeated reps times.
+func (t *truncator) more(kept, more, reps int, obj bool) {
+	if kept > 0 {
+		t.dst = append(t.dst, ',')
+	}
+	t.dst = append(t.dst, '"')
+	if obj {
+		for i := 0; i < reps; i++ {
+			t.dst = EscapeString(t.dst, t.o.Ellipsis)
+		}
+		t.dst = append(t.dst, `":"`...)
+	} else {
+		t.dst = EscapeString(t.dst, t.o.Ellipsis)
+		t.dst = append(t.dst, ' ')
+	}
+	t.dst = strconv.AppendInt(t.dst, int64(more), 10)
+	t.dst = append(t.dst, ` more"`...)
+}
+
+// ellipses returns how many times the raw (still escaped) key k repeats
+// Ellipsis once unescaped, or 0 if k is anything else.
+func (t *truncator) ellipses(k string) int {
+	t.scratch = appendUnescaped(t.scratch[:0], k)
+	e := t.o.Ellipsis
+	n := 0
+	for rest := t.scratch; len(rest) > 0; n++ {
+		if len(rest) < len(e) || string(rest[:len(e)]) != e {
+			return 0
+		}
+		rest = rest[len(e):]
+	}
+	return n
+}
+
+func (t *truncator) str(at int) (int, bool) {
+	start := len(t.dst)
+	var ok bool
+	if t.dst, at, ok = packAny(t.dst, t.in, at); !ok {
+		return at, false
+	}
+	s := t.dst[start+1 : len(t.dst)-1]
+	if t.o.MaxString <= 0 || len(s) <= t.o.MaxString {
+		return at, true
+	}
+	cut := cutString(s, t.o.MaxString)
+	t.dst = EscapeString(t.dst[:start+1+cut], t.o.Ellipsis)
+	t.dst = append(t.dst, '"')
+	return at, true
+}
+
+// cutString returns the length of the longest prefix of the valid string
+// contents s that is at most max bytes and does not split an escape, a
+// surrogate pair of escapes, or a UTF-8 sequence.
+func cutString(s []byte, max int) int {
+	at := 0
+	for at < len(s) {
+		size := 1
+		switch c := s[at]; {
+		case c == '\\' && s[at+1] != 'u':
+			size = 2
+		case c == '\\':
+			size = 6
+			u := hex4(unsafeString(s[at+2:]))
+			if u >= 0xd800 && u < 0xdc00 && at+12 <= len(s) && s[at+6] == '\\' && s[at+7] == 'u' {
+				size = 12
+			}
+		case c >= utf8.RuneSelf:
+			_, size = utf8.DecodeRune(s[at:])
+		}
+		if at+size > max {
+			break
+		}
+		at += size
+	}
+	return at
+}
+
+func (t *truncator) obj(at int) (int, bool) {
+	t.depth++
+	limit := t.limit()
+	kept, more, reps := 0, 0, 1
+	at, ok := t.walkObj(at, func(key string, at int) (int, bool, bool) {
+		if limit >= 0 && kept == limit {
+			more++
+			at, ok := any(t.in, at)
+			return at, false, ok
+		}
+		if limit >= 0 {
+			if n := t.ellipses(key); n >= reps {
+				reps = n + 1
+			}
+		}
+		kept++
+		t.dst = append(t.dst, ':')
+		at, ok := t.value(at)
+		return at, true, ok
+	})
+	if !ok {
+		return at, false
+	}
+	t.depth--
+
+	// The marker is the last member, and thus goes before the closing
+	// brace that walkObj wrote.
+	if more > 0 {
+		t.dst = t.dst[:len(t.dst)-1]
+		t.more(kept, more, reps, true)
+		t.dst = append(t.dst, '}')
+	}
+	return at, true
+}
+
+func (t *truncator) arr(at int) (int, bool) {
+	t.depth++
+	limit := t.limit()
+	kept, more := 0, 0
+	at, ok := t.walkArr(at, func(at int) (int, bool, bool) {
+		if limit >= 0 && kept == limit {
+			more++
+			at, ok := any(t.in, at)
+			return at, false, ok
+		}
+		kept++
+		at, ok := t.value(at)
+		return at, true, ok
+	})
+	if !ok {
+		return at, false
+	}
+	t.depth--
+
+	if more > 0 {
+		t.dst = t.dst[:len(t.dst)-1]
+		t.more(kept, more, 0, false)
+		t.dst = append(t.dst, ']')
+	}
+	return at, true
+}
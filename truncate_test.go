@@ -0,0 +1,108 @@
+package chkjson
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestAppendCompactTruncated(t *testing.T) {
+	for _, test := range []struct {
+		opts *TruncateOptions
+		in   string
+		exp  string // empty if invalid
+	}{
+		{nil, ` { "a" : [ 1 , "abcdef" ] } `, `{"a":[1,"abcdef"]}`},
+		{nil, `[1] 1`, ``},
+
+		{
+			&TruncateOptions{MaxString: 3, MaxElems: 2},
+			`{"a": "abcdef", "b": [1, 2, 3, 4], "c": 5}`,
+			`{"a":"abc…","b":[1,2,"… 2 more"],"…":"1 more"}`,
+		},
+		{&TruncateOptions{MaxString: 3, MaxElems: 2}, `"abc"`, `"abc"`},
+
+		{&TruncateOptions{MaxString: 3, Ellipsis: "\"..."}, `"abcd"`, `"abc\"..."`},
+
+		{
+			&TruncateOptions{MaxElems: 1, Ellipsis: "..."},
+			`{"a": "abcdef", "b": [1, 2, 3], "c": 5}`,
+			`{"a":"abcdef","...":"2 more"}`,
+		},
+		{&TruncateOptions{MaxElems: 1, Ellipsis: "..."}, `[[1, 2, 3], 4]`, `[[1,"... 2 more"],"... 1 more"]`},
+		{&TruncateOptions{MaxElems: 1, Ellipsis: "..."}, `{"...": 1, "b": 2}`, `{"...":1,"......":"1 more"}`},
+		{&TruncateOptions{MaxElems: 1, Ellipsis: "..."}, `{"…": 1, "b": 2}`, `{"…":1,"...":"1 more"}`},
+
+		{&TruncateOptions{MaxElems: 1, Ellipsis: "<\n>"}, `[1, 2]`, `[1,"<\n> 1 more"]`},
+		{
+			&TruncateOptions{MaxElems: 1, Ellipsis: "<\n>"},
+			`{"<\u000a>": 1, "b": 2}`,
+			`{"<\u000a>":1,"<\n><\n>":"1 more"}`,
+		},
+
+		{&TruncateOptions{MaxElems: 2}, `{"…": 1, "b": 2, "c": 3}`, `{"…":1,"b":2,"……":"1 more"}`},
+		{&TruncateOptions{MaxElems: 2}, `{"……": 1, "…": 2, "c": 3}`, `{"……":1,"…":2,"………":"1 more"}`},
+		{&TruncateOptions{MaxElems: 2}, `{"\u2026": 1, "…x": 2, "c": 3}`, `{"\u2026":1,"…x":2,"……":"1 more"}`},
+		{&TruncateOptions{MaxElems: 2}, `{"…": 1, "b": 2}`, `{"…":1,"b":2}`},
+
+		{&TruncateOptions{MaxString: 6}, `"a\u00e9b"`, `"a…"`},
+		{&TruncateOptions{MaxString: 6}, `{"abcdefgh": 1}`, `{"abcdefgh":1}`},
+
+		{&TruncateOptions{MaxString: 7}, `"a\u00e9b"`, `"a\u00e9…"`},
+
+		{&TruncateOptions{MaxString: 2}, `"a\nb"`, `"a…"`},
+		{&TruncateOptions{MaxString: 2}, `"aéb"`, `"a…"`},
+
+		{&TruncateOptions{MaxString: 3}, `"aéb"`, `"aé…"`},
+
+		{&TruncateOptions{MaxString: 12}, `"a\ud83d\ude00b"`, `"a…"`},
+
+		{&TruncateOptions{MaxString: 13}, `"a\ud83d\ude00b"`, `"a\ud83d\ude00…"`},
+
+		{&TruncateOptions{MaxString: 1}, `{"abcdef": 1}`, `{"abcdef":1}`},
+		{&TruncateOptions{MaxString: 1}, `"abc`, ``},
+
+		{&TruncateOptions{MaxElems: 3}, `[1, 2, 3]`, `[1,2,3]`},
+
+		{&TruncateOptions{MaxElems: 1}, `[[1, 2], {"a": 1, "b": 2}]`, `[[1,"… 1 more"],"… 1 more"]`},
+		{&TruncateOptions{MaxElems: 1}, `{"a": 1, "b": [1,]}`, ``},
+		{&TruncateOptions{MaxElems: 1}, `{"a": 1, "b" 1}`, ``},
+		{&TruncateOptions{MaxElems: 1}, `[1, 2, tru]`, ``},
+
+		{
+			&TruncateOptions{MaxDepth: 2},
+			`{"a": {"b": {"c": 1}, "d": [1, 2], "e": [], "f": {}}}`,
+			`{"a":{"b":{"…":"1 more"},"d":["… 2 more"],"e":[],"f":{}}}`,
+		},
+
+		{&TruncateOptions{MaxDepth: 1}, `[[1]]`, `[["… 1 more"]]`},
+		{&TruncateOptions{MaxDepth: 1}, `{"a": 1}`, `{"a":1}`},
+		{&TruncateOptions{MaxDepth: 1}, `[[1, 2,]]`, ``},
+	} {
+		got, ok := AppendCompactTruncatedString(nil, test.in, test.opts)
+		if ok != (test.exp != "") || string(got) != test.exp {
+			t.Errorf("«%s» %+v: got «%s» (ok? %v) != exp «%s»", test.in, test.opts, got, ok, test.exp)
+		}
+		gotb, okb := AppendCompactTruncated([]byte("x"), []byte(test.in), test.opts)
+		if okb != ok || ok && string(gotb) != "x"+string(got) {
+			t.Errorf("«%s»: got «%s» (ok? %v) as bytes != «%s» (ok? %v)", test.in, gotb, okb, got, ok)
+		}
+		// Markers must never duplicate keys.
+		if ok {
+			if err := ValidIJSON(got); err != nil {
+				t.Errorf("«%s» %+v: truncated «%s» is not I-JSON: %v", test.in, test.opts, got, err)
+			}
+		}
+	}
+
+	for i := 0; i < 3; i++ {
+		big := genBig()
+		exp, _ := AppendCompact(nil, big)
+		if got, ok := AppendCompactTruncated(nil, big, nil); !ok || string(got) != string(exp) {
+			t.Errorf("genBig: got ok? %v, equal? %v", ok, string(got) == string(exp))
+		}
+		opts := &TruncateOptions{MaxString: 5, MaxElems: 2, MaxDepth: 3}
+		if got, ok := AppendCompactTruncated(nil, big, opts); !ok || !json.Valid(got) {
+			t.Errorf("genBig truncated: got ok? %v, valid? %v", ok, json.Valid(got))
+		}
+	}
+}
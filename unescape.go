@@ -0,0 +1,181 @@
+package chkjson
+
+import (
+	"bytes"
+	"strings"
+	"unicode/utf8"
+)
+
+// This file contains helpers for working with the unescaped contents of
+// strings. All functions take the body of a string (that is, without the
+// surrounding quotes) that has already been validated.
+//
+// As with encoding/json, \u escapes of unpaired surrogate halves unescape to
+// the replacement character U+FFFD. Raw bytes are never modified.
+
+// unescapeUnit unescapes the unit beginning at s[i] into buf, returning the
+// number of bytes in buf and the offset of the next unit. A unit is either a
+// raw byte or a full escape sequence (including surrogate pairs).
+func unescapeUnit(s string, i int, buf *[utf8.UTFMax]byte) (int, int) {
+	if s[i] != '\\' {
+		buf[0] = s[i]
+		return 1, i + 1
+	}
+	switch s[i+1] {
+	case 'b':
+		buf[0] = '\b'
+	case 'f':
+		buf[0] = '\f'
+	case 'n':
+		buf[0] = '\n'
+	case 'r':
+		buf[0] = '\r'
+	case 't':
+		buf[0] = '\t'
+	case 'u':
+		u, next := unescapeU(s, i)
+		return utf8.EncodeRune(buf[:], u), next
+	default: // \\, \/, \"
+		buf[0] = s[i+1]
+	}
+	return 1, i + 2
+}
+
+// unescapeU unescapes the \u escape at s[i], returning the rune and the offset
+// after the escape. If the escape is a high surrogate followed by a low
+// surrogate escape, the pair is combined.
+func unescapeU(s string, i int) (rune, int) {
+	u := hex4(s[i+2:])
+	switch {
+	case u < 0xd800 || u >= 0xe000:
+		return u, i + 6
+	case u < 0xdc00 && len(s) >= i+12 && s[i+6] == '\\' && s[i+7] == 'u':
+		if low := hex4(s[i+8:]); low >= 0xdc00 && low < 0xe000 {
+			return 0x10000 + (u-0xd800)<<10 + (low - 0xdc00), i + 12
+		}
+	}
+	return utf8.RuneError, i + 6
+}
+
+// appendUnescaped appends the unescaped contents of the string body s to dst.
+func appendUnescaped(dst []byte, s string) []byte {
+	var buf [utf8.UTFMax]byte
+	st := 0
+	for i := 0; i < len(s); {
+		if s[i] != '\\' {
+			i++
+			continue
+		}
+		dst = append(dst, s[st:i]...)
+		var n int
+		n, i = unescapeUnit(s, i, &buf)
+		dst = append(dst, buf[:n]...)
+		st = i
+	}
+	return append(dst, s[st:]...)
+}
+
+// appendMinimal appends the string body s to dst rewritten to its shortest
+// escaping. Escapes are decoded and re-escaped with EscapeString using the
+// short \b and \f escapes. Escapes of unpaired surrogate halves are kept with
+// their hex digits lowercased.
+//
+// Every escape is rewritten to at most as many bytes as it took, meaning s can
+// be rewritten in place.
+func appendMinimal(dst []byte, s string) []byte {
+	var buf [utf8.UTFMax]byte
+	st := 0
+	for i := 0; i < len(s); {
+		if s[i] != '\\' {
+			i++
+			continue
+		}
+		dst = append(dst, s[st:i]...)
+
+		var n int
+		if s[i+1] == 'u' {
+			if u := hex4(s[i+2:]); u >= 0xd800 && u < 0xe000 {
+				if _, next := unescapeU(s, i); next == i+6 {
+					dst = append(dst, '\\', 'u')
+					for j := i + 2; j < i+6; j++ {
+						c := s[j]
+						if c >= 'A' && c <= 'F' {
+							c += 'a' - 'A'
+						}
+						dst = append(dst, c)
+					}
+					i += 6
+					st = i
+					continue
+				}
+			}
+		}
+		n, i = unescapeUnit(s, i, &buf)
+		dst = EscapeString(dst, unsafeString(buf[:n]), escapeShort)
+		st = i
+	}
+	return append(dst, s[st:]...)
+}
+
+// unescapedIs returns whether the string body s equals the plain, unescaped
+// string plain once unescaped.
+func unescapedIs(s, plain string) bool {
+	var buf [utf8.UTFMax]byte
+	j := 0
+	for i := 0; i < len(s); {
+		var n int
+		n, i = unescapeUnit(s, i, &buf)
+		if len(plain)-j < n || string(buf[:n]) != plain[j:j+n] {
+			return false
+		}
+		j += n
+	}
+	return j == len(plain)
+}
+
+// unescapedEqual returns whether the string bodies a and b are equal once
+// unescaped.
+func unescapedEqual(a, b string) bool {
+	return a == b || unescapedCompare(a, b) == 0
+}
+
+// unescapedCompare compares the string bodies a and b byte by byte once
+// unescaped, returning -1, 0, or 1 as strings.Compare does.
+func unescapedCompare(a, b string) int {
+	if strings.IndexByte(a, '\\') < 0 && strings.IndexByte(b, '\\') < 0 {
+		return strings.Compare(a, b)
+	}
+
+	var abuf, bbuf [utf8.UTFMax]byte
+	var apend, bpend []byte // unescaped bytes not yet compared
+	var ai, bi int
+	for {
+		if len(apend) == 0 {
+			if ai == len(a) {
+				if len(bpend) == 0 && bi == len(b) {
+					return 0
+				}
+				return -1
+			}
+			var n int
+			n, ai = unescapeUnit(a, ai, &abuf)
+			apend = abuf[:n]
+		}
+		if len(bpend) == 0 {
+			if bi == len(b) {
+				return 1
+			}
+			var n int
+			n, bi = unescapeUnit(b, bi, &bbuf)
+			bpend = bbuf[:n]
+		}
+		n := len(apend)
+		if len(bpend) < n {
+			n = len(bpend)
+		}
+		if c := bytes.Compare(apend[:n], bpend[:n]); c != 0 {
+			return c
+		}
+		apend, bpend = apend[n:], bpend[n:]
+	}
+}
@@ -0,0 +1,57 @@
+package chkjson
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestUnescape(t *testing.T) {
+	for _, test := range []string{
+		``,
+		`foo`,
+		`\"\\\/\b\f\n\r\t`,
+		`A\u00e9 \uffff`,
+		`\ud83d\ude00`,
+		`\uD83D\uDE00`,
+		`a\ud800b`,
+		`\ud800A`,
+		`\ud800\ud800\udc00`,
+		`\udc00`,
+		"raw \xe2\x80\xa8 bytes",
+	} {
+		var exp string
+		if err := json.Unmarshal([]byte(`"`+test+`"`), &exp); err != nil {
+			t.Fatalf("%s: unable to unmarshal: %v", test, err)
+		}
+		if got := string(appendUnescaped(nil, test)); got != exp {
+			t.Errorf("%s: got %q != exp %q", test, got, exp)
+		}
+	}
+
+	for _, test := range []struct {
+		a, b string
+		exp  bool
+	}{
+		{``, ``, true},
+		{`a`, ``, false},
+		{``, `a`, false},
+		{`a`, `a`, true},
+		{`ab`, `ab`, true},
+		{`ab`, `ac`, false},
+		{`\u00e9`, "\xc3\xa9", true},
+		{`\u00e9`, "\xc3", false},
+		{`\ud83d\ude00`, "\xf0\x9f\x98\x80", true},
+		{`\ud83d\ude00x`, "\xf0\x9f\x98\x80", false},
+		{`\ud800`, "\xef\xbf\xbd", true}, // as with encoding/json
+	} {
+		if got := unescapedEqual(test.a, test.b); got != test.exp {
+			t.Errorf("%s == %s? got %v, exp %v", test.a, test.b, got, test.exp)
+		}
+		if got := unescapedEqual(test.b, test.a); got != test.exp {
+			t.Errorf("%s == %s? got %v, exp %v", test.b, test.a, got, test.exp)
+		}
+		if plain := string(appendUnescaped(nil, test.b)); unescapedIs(test.a, plain) != test.exp {
+			t.Errorf("%s is %q? got %v, exp %v", test.a, plain, !test.exp, test.exp)
+		}
+	}
+}
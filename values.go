@@ -0,0 +1,89 @@
+package chkjson
+
+import (
+	"unsafe"
+)
+
+// ValidPrefix returns the number of bytes that the first JSON value in b
+// takes, including any leading whitespace, and whether that value is valid.
+// Unlike Valid, anything may follow the value.
+//
+// Numbers end at the first byte that cannot continue them, meaning "12" is
+// one value, not two. If b does not begin with a valid value, this returns 0
+// and false.
+func ValidPrefix(b []byte) (int, bool) {
+	return ValidPrefixString(*(*string)(unsafe.Pointer(&b)))
+}
+
+// ValidPrefixString is exactly like ValidPrefix, but for strings.
+func ValidPrefixString(s string) (int, bool) {
+	at, ok := any(s, 0)
+	if !ok {
+		return 0, false
+	}
+	return at, true
+}
+
+// Values iterates over a buffer of concatenated JSON values, such as
+// `{"a":1}{"b":2}` or `1 2 3`, without allocating. The zero value iterates
+// over nothing; use NewValues or Reset to iterate over a buffer.
+type Values struct {
+	b       []byte
+	at      int
+	invalid bool
+}
+
+// NewValues returns a Values iterating over b.
+func NewValues(b []byte) Values {
+	return Values{b: b}
+}
+
+// Reset resets v to iterate over b.
+func (v *Values) Reset(b []byte) {
+	*v = Values{b: b}
+}
+
+// Next returns the next value in the buffer, with leading whitespace trimmed.
+// The returned slice aliases the buffer.
+//
+// Next returns nil once only whitespace remains or if the next value is
+// invalid. Use Invalid to tell the two apart.
+func (v *Values) Next() []byte {
+	if v.invalid {
+		return nil
+	}
+	s := *(*string)(unsafe.Pointer(&v.b))
+	start := v.at
+	for ; start < len(s); start++ {
+		switch s[start] {
+		case ' ', '\r', '\t', '\n':
+			continue
+		}
+		break
+	}
+	if start == len(s) {
+		v.at = start
+		return nil
+	}
+	end, ok := any(s, start)
+	if !ok {
+		v.at = start
+		v.invalid = true
+		return nil
+	}
+	v.at = end
+	return v.b[start:end:end]
+}
+
+// Invalid returns whether iteration stopped at an invalid value rather than
+// at the end of the buffer.
+func (v *Values) Invalid() bool {
+	return v.invalid
+}
+
+// Offset returns the offset into the buffer following the last value returned
+// from Next. Once Next returns nil, this is the offset of the invalid value
+// or the length of the buffer.
+func (v *Values) Offset() int {
+	return v.at
+}
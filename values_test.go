@@ -0,0 +1,76 @@
+package chkjson
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestValidPrefix(t *testing.T) {
+	for _, test := range []struct {
+		in string
+		n  int
+		ok bool
+	}{
+		{"", 0, false},
+		{"  ", 0, false},
+		{"1", 1, true},
+		{"12 3", 2, true},
+		{` {"a":1}{"b":2}`, 8, true},
+		{"true false", 4, true},
+		{"[1,2]]", 5, true},
+		{`"a" z`, 3, true},
+		{"tru", 0, false},
+		{"[1,", 0, false},
+		{"z1", 0, false},
+	} {
+		n, ok := ValidPrefix([]byte(test.in))
+		if n != test.n || ok != test.ok {
+			t.Errorf("%q: got %d, %v != exp %d, %v", test.in, n, ok, test.n, test.ok)
+		}
+		if sn, sok := ValidPrefixString(test.in); sn != n || sok != ok {
+			t.Errorf("%q: got %d, %v as string != %d, %v", test.in, sn, sok, n, ok)
+		}
+	}
+}
+
+func TestValues(t *testing.T) {
+	for _, test := range []struct {
+		in      string
+		exp     []string
+		invalid bool
+		offset  int
+	}{
+		{"", nil, false, 0},
+		{" \n ", nil, false, 3},
+		{`{"a":1}{"b":2}`, []string{`{"a":1}`, `{"b":2}`}, false, 14},
+		{"1 2\n3\r\n", []string{"1", "2", "3"}, false, 7},
+		{` "x"[ 1 ]null `, []string{`"x"`, "[ 1 ]", "null"}, false, 14},
+		{`1 {"a" 2`, []string{"1"}, true, 2},
+		{`1 z`, []string{"1"}, true, 2},
+	} {
+		v := NewValues([]byte(test.in))
+		var got []string
+		for b := v.Next(); b != nil; b = v.Next() {
+			got = append(got, string(b))
+		}
+		if !reflect.DeepEqual(got, test.exp) {
+			t.Errorf("%q: got %q != exp %q", test.in, got, test.exp)
+		}
+		if v.Invalid() != test.invalid || v.Offset() != test.offset {
+			t.Errorf("%q: got invalid? %v at %d != exp %v at %d", test.in, v.Invalid(), v.Offset(), test.invalid, test.offset)
+		}
+		if v.Next() != nil {
+			t.Errorf("%q: unexpected value after end", test.in)
+		}
+	}
+
+	in := []byte(`{"a":[1,2]} "b" 3`)
+	var v Values
+	if allocs := testing.AllocsPerRun(100, func() {
+		v.Reset(in)
+		for v.Next() != nil {
+		}
+	}); allocs != 0 {
+		t.Errorf("got %v allocs != exp 0", allocs)
+	}
+}
@@ -0,0 +1,115 @@
+package chkjson
+
+// walker compacts objects and arrays like packAny, but lets its user decide
+// what to write for each object member and array element, and whether to keep
+// it at all. The compactors that drop, select, or rewrite parts of their input
+// embed a walker.
+type walker struct {
+	in  string
+	dst []byte
+}
+
+// walkObj compacts the object at in[at], which must be a '{'.
+//
+// For each member, walkObj writes the key to dst and checks that a colon
+// follows it, and then calls member with the key as written, without quotes,
+// and the offset just past the colon. member writes the colon and the value,
+// or whatever should replace them, and returns the offset just past the value
+// and whether to keep the member. The key is only valid until member grows
+// dst.
+//
+// Members that are not kept are cut from dst along with their comma. We write
+// the comma before each member we keep rather than after each member we see,
+// so that cutting a member never leaves a dangling comma.
+func (w *walker) walkObj(at int, member func(key string, at int) (end int, keep, ok bool)) (int, bool) {
+	in := w.in
+	w.dst = append(w.dst, '{')
+	if at = skipSpace(in, at+1); at < len(in) && in[at] == '}' {
+		w.dst = append(w.dst, '}')
+		return at + 1, true
+	}
+
+	kept := 0
+	var keep, ok bool
+	for {
+		if at == len(in) || in[at] != '"' {
+			return at, false
+		}
+		start := len(w.dst)
+		if kept > 0 {
+			w.dst = append(w.dst, ',')
+		}
+		keyStart := len(w.dst)
+		if w.dst, at, ok = packAny(w.dst, in, at); !ok {
+			return at, false
+		}
+		if at = skipSpace(in, at); at == len(in) || in[at] != ':' {
+			return at, false
+		}
+		if at, keep, ok = member(unsafeString(w.dst[keyStart+1:len(w.dst)-1]), at+1); !ok {
+			return at, false
+		}
+		if keep {
+			kept++
+		} else {
+			w.dst = w.dst[:start]
+		}
+
+		if at = skipSpace(in, at); at == len(in) {
+			return at, false
+		}
+		switch in[at] {
+		case ',':
+			at = skipSpace(in, at+1)
+		case '}':
+			w.dst = append(w.dst, '}')
+			return at + 1, true
+		default:
+			return at, false
+		}
+	}
+}
+
+// walkArr compacts the array at in[at], which must be a '['. For each
+// element, walkArr calls elem with the element's offset; elem writes the
+// element, or whatever should replace it, and returns the offset just past the
+// element and whether to keep it. As in walkObj, elements that are not kept
+// are cut from dst along with their comma.
+func (w *walker) walkArr(at int, elem func(at int) (end int, keep, ok bool)) (int, bool) {
+	in := w.in
+	w.dst = append(w.dst, '[')
+	if at = skipSpace(in, at+1); at < len(in) && in[at] == ']' {
+		w.dst = append(w.dst, ']')
+		return at + 1, true
+	}
+
+	kept := 0
+	var keep, ok bool
+	for {
+		start := len(w.dst)
+		if kept > 0 {
+			w.dst = append(w.dst, ',')
+		}
+		if at, keep, ok = elem(at); !ok {
+			return at, false
+		}
+		if keep {
+			kept++
+		} else {
+			w.dst = w.dst[:start]
+		}
+
+		if at = skipSpace(in, at); at == len(in) {
+			return at, false
+		}
+		switch in[at] {
+		case ',':
+			at++
+		case ']':
+			w.dst = append(w.dst, ']')
+			return at + 1, true
+		default:
+			return at, false
+		}
+	}
+}